@@ -2,13 +2,18 @@ package app
 
 import (
 	"os"
+	"user-api/config"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func init() {
+// ConfigureLogging applies the log settings. They were validated by config.Load.
+func ConfigureLogging(logConfig config.LogConfig) {
 	log.SetOutput(os.Stdout)
-	//log.SetFormatter(&log.JSONFormatter{})
-	log.SetLevel(log.DebugLevel)
+	if logConfig.Format == "json" {
+		log.SetFormatter(&log.JSONFormatter{})
+	}
+	level, _ := log.ParseLevel(logConfig.Level)
+	log.SetLevel(level)
 	log.Info("Starting logger system")
-}
\ No newline at end of file
+}
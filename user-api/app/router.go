@@ -1,24 +1,57 @@
 package app
 
 import (
+	"fmt"
+	"net/http"
+	"time"
+	"user-api/config"
+	userController "user-api/controller"
+	"user-api/middleware"
+	e "user-api/utils/errors"
+	"user-api/utils/requestid"
+	"user-api/utils/token"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
 
-var (
-	router *gin.Engine
-)
-
-func init() {
-	router = gin.Default()
-	router.Use(cors.Default())
+// Routes reachable without an access token. Every other route requires one.
+var publicRoutes = []middleware.Route{
+	{Method: http.MethodPost, Path: "/user-api/user"}, // Sign In
+	{Method: http.MethodPost, Path: "/user-api/login"},
+	{Method: http.MethodPost, Path: "/user-api/token/refresh"},
+	{Method: http.MethodPost, Path: "/user-api/logout"},
+	{Method: http.MethodGet, Path: "/user-api/verify"},
+	{Method: http.MethodPost, Path: "/user-api/password/forgot"},
+	{Method: http.MethodPost, Path: "/user-api/password/reset"},
+	{Method: http.MethodGet, Path: "/user-api/health"},
 }
 
-func StartRoute() {
-	mapUrls()
+// NewRouter builds the engine with the middlewares and every route of the API.
+func NewRouter(serverConfig config.ServerConfig, signer *token.Signer, users *userController.UserController, tokens *userController.TokenController, audit *userController.AuditController) *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.RequestID(), gin.Logger(), middleware.Recovery(), middleware.Errors())
+	router.NoRoute(func(c *gin.Context) {
+		c.Error(e.NewNotFoundApiError(e.MsgResourceNotFound))
+	})
 
-	log.Info("Starting server")
-	router.Run(":8080")
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowAllOrigins = true
+	corsConfig.AddAllowHeaders("Authorization", "If-Match", requestid.Header)
+	corsConfig.AddExposeHeaders("ETag", requestid.Header)
+	router.Use(cors.New(corsConfig))
 
+	router.Use(middleware.Timeout(time.Duration(serverConfig.RequestTimeout)))
+	router.Use(middleware.Authenticate(signer, publicRoutes))
+
+	mapUrls(router, users, tokens, audit)
+	return router
+}
+
+func StartRoute(router *gin.Engine, serverConfig config.ServerConfig) {
+	log.Info("Starting server")
+	if err := router.Run(fmt.Sprintf(":%d", serverConfig.Port)); err != nil {
+		log.Fatal(err)
+	}
 }
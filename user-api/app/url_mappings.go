@@ -1,19 +1,41 @@
 package app
 
 import (
+	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 
 	userController "user-api/controller"
+	"user-api/middleware"
 )
 
-func mapUrls() {
+func mapUrls(router *gin.Engine, users *userController.UserController, tokens *userController.TokenController, audit *userController.AuditController) {
 
 	// Users Mapping
-	router.GET("/user-api/user/:id", userController.GetUserById)
-	router.GET("/user-api/user", userController.GetUsers)
-	router.POST("/user-api/user", userController.UserInsert) // Sign In
-	router.DELETE("user-api/user/:id", userController.DeleteUser)
-	router.PUT("user-api/user/:id", userController.UpdateUser)
+	router.GET("/user-api/user/:id", middleware.RequireSelfOrAdmin("id"), users.GetUserById)
+	router.GET("/user-api/user", middleware.RequireAdmin(), users.GetUsers)
+	router.GET("/user-api/user/search", middleware.RequireAdmin(), users.SearchUsers)
+	router.POST("/user-api/user", users.UserInsert) // Sign In
+	router.DELETE("user-api/user/:id", middleware.RequireAdmin(), users.DeleteUser)
+	router.POST("/user-api/user/:id/restore", middleware.RequireAdmin(), users.RestoreUser)
+	router.PUT("user-api/user/:id", middleware.RequireSelfOrAdmin("id"), users.UpdateUser)
+	router.PATCH("/user-api/user/:id", middleware.RequireSelfOrAdmin("id"), users.PatchUser)
+
+	// Password Mapping
+	router.PUT("/user-api/user/:id/password", middleware.RequireSelfOrAdmin("id"), users.ChangePassword)
+	router.POST("/user-api/password/forgot", users.ForgotPassword)
+	router.POST("/user-api/password/reset", users.ResetPassword)
+
+	// Auth Mapping
+	router.POST("/user-api/login", users.Login)
+	router.POST("/user-api/token/refresh", tokens.RefreshToken)
+	router.POST("/user-api/logout", tokens.Logout)
+	router.GET("/user-api/verify", users.VerifyEmail)
+
+	// Audit Mapping
+	router.GET("/user-api/audit", middleware.RequireAdmin(), audit.GetAuditEntries)
+
+	// Health Mapping
+	router.GET("/user-api/health", userController.Health)
 
 	log.Info("Finishing mappings configurations")
 }
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"user-api/model"
+	"user-api/utils/requestid"
+)
+
+// Redacted is recorded in place of the values of secret fields.
+const Redacted = "[REDACTED]"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries the id of the user making the
+// request, who is recorded as the actor of the changes made with it.
+func NewContext(ctx context.Context, actorId int) context.Context {
+	return context.WithValue(ctx, contextKey{}, actorId)
+}
+
+// ActorFromContext returns the actor stored by NewContext.
+func ActorFromContext(ctx context.Context) (int, bool) {
+	actorId, ok := ctx.Value(contextKey{}).(int)
+	return actorId, ok
+}
+
+// fields are the audited fields of a user, by the name the API gives them. The
+// search text, the version and the deletion mark follow from the others.
+var fields = []struct {
+	name   string
+	secret bool
+	value  func(user model.User) interface{}
+}{
+	{"name", false, func(user model.User) interface{} { return user.Name }},
+	{"last_name", false, func(user model.User) interface{} { return user.LastName }},
+	{"username", false, func(user model.User) interface{} { return user.UserName }},
+	{"email", false, func(user model.User) interface{} { return user.Email }},
+	{"phone", false, func(user model.User) interface{} { return user.Phone }},
+	{"address", false, func(user model.User) interface{} { return user.Address }},
+	{"type", false, func(user model.User) interface{} { return user.Type }},
+	{"email_verified", false, func(user model.User) interface{} { return user.EmailVerified }},
+	{"password", true, func(user model.User) interface{} { return user.Password }},
+}
+
+// Diff returns the fields that differ between two versions of a user. before is
+// nil for a created user and after for a deleted one, and then every field is
+// listed. Secret fields only tell that they changed. A purge, which has neither,
+// changes no field.
+func Diff(before *model.User, after *model.User) []model.AuditChange {
+	changes := []model.AuditChange{}
+	if before == nil && after == nil {
+		return changes
+	}
+	for _, field := range fields {
+		if before != nil && after != nil && field.value(*before) == field.value(*after) {
+			continue
+		}
+
+		change := model.AuditChange{Field: field.name}
+		if before != nil {
+			change.Before = value(field.value(*before), field.secret)
+		}
+		if after != nil {
+			change.After = value(field.value(*after), field.secret)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func value(v interface{}, secret bool) json.RawMessage {
+	if secret {
+		v = Redacted
+	}
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// NewEntry builds the entry of a change made to a user in the request of ctx.
+func NewEntry(ctx context.Context, action string, targetId int, before *model.User, after *model.User) model.AuditEntry {
+	entry := model.AuditEntry{
+		Action:    action,
+		TargetId:  targetId,
+		Changes:   Diff(before, after),
+		RequestId: requestid.FromContext(ctx),
+		CreatedAt: time.Now(),
+	}
+	if actorId, ok := ActorFromContext(ctx); ok {
+		entry.ActorId = &actorId
+	}
+	return entry
+}
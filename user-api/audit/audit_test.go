@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"user-api/model"
+	"user-api/utils/requestid"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff_ChangedFields(t *testing.T) {
+	before := model.User{Id: 1, Name: "John", UserName: "jdoe", Email: "jdoe@example.com", Password: "old-hash", Version: 1}
+	after := before
+	after.Email = "john@example.com"
+	after.Password = "new-hash"
+	after.Version = 2
+
+	changes := Diff(&before, &after)
+
+	assert.Equal(t, []model.AuditChange{
+		{Field: "email", Before: json.RawMessage(`"jdoe@example.com"`), After: json.RawMessage(`"john@example.com"`)},
+		{Field: "password", Before: json.RawMessage(`"[REDACTED]"`), After: json.RawMessage(`"[REDACTED]"`)},
+	}, changes)
+}
+
+func TestDiff_Unchanged(t *testing.T) {
+	user := model.User{Id: 1, Name: "John", Password: "hash"}
+
+	assert.Empty(t, Diff(&user, &user))
+}
+
+func TestDiff_CreatedAndDeleted(t *testing.T) {
+	user := model.User{Id: 1, Name: "John", Password: "hash"}
+
+	created := Diff(nil, &user)
+	assert.Len(t, created, len(fields))
+	for _, change := range created {
+		assert.Nil(t, change.Before)
+		assert.NotNil(t, change.After)
+		assert.NotContains(t, string(change.After), "hash")
+	}
+
+	deleted := Diff(&user, nil)
+	assert.Len(t, deleted, len(fields))
+	for _, change := range deleted {
+		assert.NotNil(t, change.Before)
+		assert.Nil(t, change.After)
+	}
+
+	// A purge has no version of the user to compare
+	assert.Empty(t, Diff(nil, nil))
+}
+
+func TestNewEntry(t *testing.T) {
+	before := model.User{Id: 2, Name: "John"}
+	after := model.User{Id: 2, Name: "Johnny"}
+
+	ctx := NewContext(requestid.NewContext(context.Background(), "req-1"), 7)
+	entry := NewEntry(ctx, model.AuditActionUpdate, 2, &before, &after)
+
+	assert.Equal(t, model.AuditActionUpdate, entry.Action)
+	assert.Equal(t, 2, entry.TargetId)
+	assert.Equal(t, "req-1", entry.RequestId)
+	if assert.NotNil(t, entry.ActorId) {
+		assert.Equal(t, 7, *entry.ActorId)
+	}
+	assert.Len(t, entry.Changes, 1)
+	assert.False(t, entry.CreatedAt.IsZero())
+
+	// Without a logged in caller there is no actor
+	entry = NewEntry(context.Background(), model.AuditActionCreate, 2, nil, &after)
+	assert.Nil(t, entry.ActorId)
+	assert.Empty(t, entry.RequestId)
+}
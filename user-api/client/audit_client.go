@@ -0,0 +1,91 @@
+package user
+
+import (
+	"context"
+	"time"
+	"user-api/model"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// AuditClientInterface stores the audit log of the changes to users. Entries are
+// never changed nor removed, not even when their user is purged.
+type AuditClientInterface interface {
+	InsertAuditEntry(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error)
+	GetAuditEntries(ctx context.Context, query AuditQuery) (AuditPage, error)
+}
+
+// AuditQuery selects a page of the audit log, newest entries first. Zero fields
+// don't filter.
+type AuditQuery struct {
+	Limit    int
+	Offset   int
+	ActorId  int
+	TargetId int
+	Action   string
+	From     time.Time // Inclusive
+	To       time.Time // Exclusive
+}
+
+// AuditPage is a page of the audit log and the number of entries of every page.
+type AuditPage struct {
+	Entries []model.AuditEntry
+	Total   int
+}
+
+type AuditClient struct {
+	db *gorm.DB
+}
+
+func NewAuditClient(db *gorm.DB) *AuditClient {
+	return &AuditClient{db: db}
+}
+
+func (c *AuditClient) InsertAuditEntry(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error) {
+	result := c.db.WithContext(ctx).Create(&entry)
+
+	if result.Error != nil {
+		log.Error("Error inserting audit entry: ", result.Error)
+		return entry, translateError("audit_entries", result.Error)
+	}
+	return entry, nil
+}
+
+func (c *AuditClient) GetAuditEntries(ctx context.Context, query AuditQuery) (AuditPage, error) {
+	var page AuditPage
+	if query.Limit <= 0 {
+		query.Limit = DefaultPageSize
+	}
+
+	db := c.db.WithContext(ctx).Model(&model.AuditEntry{})
+	if query.ActorId != 0 {
+		db = db.Where("actor_id = ?", query.ActorId)
+	}
+	if query.TargetId != 0 {
+		db = db.Where("target_id = ?", query.TargetId)
+	}
+	if query.Action != "" {
+		db = db.Where("action = ?", query.Action)
+	}
+	if !query.From.IsZero() {
+		db = db.Where("created_at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		db = db.Where("created_at < ?", query.To)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		log.Error("Error counting audit entries: ", err)
+		return page, translateError("audit_entries", err)
+	}
+	page.Total = int(total)
+
+	err := db.Order("created_at DESC").Order("id DESC").Limit(query.Limit).Offset(query.Offset).Find(&page.Entries).Error
+	if err != nil {
+		log.Error("Error getting audit entries: ", err)
+		return page, translateError("audit_entries", err)
+	}
+	return page, nil
+}
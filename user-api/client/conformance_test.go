@@ -0,0 +1,568 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+	"user-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clients is one implementation of every client interface, all sharing a storage.
+type clients struct {
+	users         UserClientInterface
+	refreshTokens RefreshTokenClientInterface
+	oneTimeTokens OneTimeTokenClientInterface
+	audit         AuditClientInterface
+}
+
+// implementations are the storages every conformance test runs against. They must
+// be indistinguishable to the services.
+var implementations = map[string]func(t *testing.T) clients{
+	"gorm": func(t *testing.T) clients {
+		db := setupTestDB(t)
+		return clients{NewUserClient(db), NewRefreshTokenClient(db), NewOneTimeTokenClient(db), NewAuditClient(db)}
+	},
+	"memory": func(t *testing.T) clients {
+		store := NewMemoryStore()
+		return clients{NewMemoryUserClient(store), NewMemoryRefreshTokenClient(store), NewMemoryOneTimeTokenClient(store), NewMemoryAuditClient(store)}
+	},
+}
+
+func runConformance(t *testing.T, test func(t *testing.T, c clients)) {
+	for name, newClients := range implementations {
+		t.Run(name, func(t *testing.T) {
+			test(t, newClients(t))
+		})
+	}
+}
+
+func insertUsers(t *testing.T, c clients, users ...model.User) model.Users {
+	inserted := make(model.Users, 0, len(users))
+	for _, user := range users {
+		user, err := c.users.InsertUser(ctx, user, model.AuditEntry{})
+		require.NoError(t, err)
+		require.NotZero(t, user.Id)
+		inserted = append(inserted, user)
+	}
+	return inserted
+}
+
+func TestConformance_InsertAndGet(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		users := insertUsers(t, c,
+			model.User{Name: "Ana", UserName: "ana", Email: "ana@example.com"},
+			model.User{Name: "Bruno", UserName: "bruno", Email: "bruno@example.com"},
+		)
+
+		assert.NotEqual(t, users[0].Id, users[1].Id)
+		user, err := c.users.GetUserById(ctx, users[0].Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "ana", user.UserName)
+		_, err = c.users.GetUserById(ctx, 999)
+		assert.Equal(t, ErrNotFound, err)
+
+		user, err = c.users.GetUserByUsername(ctx, "bruno")
+		assert.NoError(t, err)
+		assert.Equal(t, users[1].Id, user.Id)
+		assert.NotEmpty(t, user.SearchText)
+
+		user, err = c.users.FindUserByEmail(ctx, "ana@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, users[0].Id, user.Id)
+
+		_, err = c.users.GetUserByUsername(ctx, "nadie")
+		assert.Equal(t, ErrNotFound, err)
+		_, err = c.users.FindUserByEmail(ctx, "nadie@example.com")
+		assert.Equal(t, ErrNotFound, err)
+
+		taken, err := c.users.EmailTaken(ctx, "ana@example.com")
+		assert.NoError(t, err)
+		assert.True(t, taken)
+		taken, err = c.users.EmailTaken(ctx, "nadie@example.com")
+		assert.NoError(t, err)
+		assert.False(t, taken)
+	})
+}
+
+func TestConformance_Uniqueness(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		users := insertUsers(t, c,
+			model.User{UserName: "ana", Email: "ana@example.com"},
+			model.User{UserName: "bruno", Email: "bruno@example.com"},
+		)
+
+		_, err := c.users.InsertUser(ctx, model.User{UserName: "otra", Email: "ana@example.com"}, model.AuditEntry{})
+		assertDuplicate(t, err, "email")
+		_, err = c.users.InsertUser(ctx, model.User{UserName: "ana", Email: "otra@example.com"}, model.AuditEntry{})
+		assertDuplicate(t, err, "user_name")
+
+		// Deleted users keep their email and username until they are purged
+		require.NoError(t, c.users.DeleteUser(ctx, users[0].Id, users[0].Version, model.AuditEntry{}))
+		_, err = c.users.InsertUser(ctx, model.User{UserName: "ana", Email: "otra@example.com"}, model.AuditEntry{})
+		assertDuplicate(t, err, "user_name")
+		taken, err := c.users.EmailTaken(ctx, "ana@example.com")
+		assert.NoError(t, err)
+		assert.True(t, taken)
+
+		bruno := users[1]
+		bruno.Email = "ana@example.com"
+		assertDuplicate(t, c.users.UpdateUser(ctx, bruno, model.AuditEntry{}), "email")
+		stored, err := c.users.GetUserById(ctx, bruno.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "bruno@example.com", stored.Email)
+	})
+}
+
+func TestConformance_UpdateUser(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		user := insertUsers(t, c, model.User{Name: "Ana", UserName: "ana", Email: "ana@example.com"})[0]
+
+		user.Name = "Anabel"
+		user.EmailVerified = true
+		require.NoError(t, c.users.UpdateUser(ctx, user, model.AuditEntry{}))
+
+		updated, err := c.users.GetUserById(ctx, user.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "Anabel", updated.Name)
+		assert.True(t, updated.EmailVerified)
+		assert.Contains(t, updated.SearchText, "anabel")
+	})
+}
+
+func TestConformance_Versions(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		user := insertUsers(t, c, model.User{Name: "Ana", UserName: "ana", Email: "ana@example.com"})[0]
+		assert.Equal(t, 1, user.Version)
+
+		first, second := user, user
+		first.Name = "Anabel"
+		require.NoError(t, c.users.UpdateUser(ctx, first, model.AuditEntry{}))
+		second.Name = "Ana María"
+		assert.Equal(t, ErrStaleVersion, c.users.UpdateUser(ctx, second, model.AuditEntry{}))
+
+		stored, err := c.users.GetUserById(ctx, user.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "Anabel", stored.Name)
+		assert.Equal(t, 2, stored.Version)
+
+		assert.Equal(t, ErrStaleVersion, c.users.DeleteUser(ctx, user.Id, 1, model.AuditEntry{}))
+		require.NoError(t, c.users.DeleteUser(ctx, user.Id, 2, model.AuditEntry{}))
+		assert.Equal(t, ErrNotFound, c.users.UpdateUser(ctx, stored, model.AuditEntry{}))
+
+		restored, err := c.users.RestoreUser(ctx, user.Id, model.AuditEntry{})
+		require.NoError(t, err)
+		assert.Equal(t, 3, restored.Version)
+		stored, err = c.users.GetUserById(ctx, user.Id)
+		require.NoError(t, err)
+		assert.Equal(t, 3, stored.Version)
+
+		missing := model.User{Id: 999, UserName: "nadie", Email: "nadie@example.com", Version: 1}
+		assert.Equal(t, ErrNotFound, c.users.UpdateUser(ctx, missing, model.AuditEntry{}))
+	})
+}
+
+func TestConformance_DeleteAndRestore(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		user := insertUsers(t, c, model.User{UserName: "ana", Email: "ana@example.com"})[0]
+
+		_, err := c.users.RestoreUser(ctx, user.Id, model.AuditEntry{})
+		assert.Equal(t, ErrNotFound, err)
+
+		require.NoError(t, c.users.DeleteUser(ctx, user.Id, user.Version, model.AuditEntry{}))
+		_, err = c.users.GetUserById(ctx, user.Id)
+		assert.Equal(t, ErrNotFound, err)
+		_, err = c.users.GetUserByUsername(ctx, "ana")
+		assert.Equal(t, ErrNotFound, err)
+		assert.Equal(t, ErrNotFound, c.users.DeleteUser(ctx, user.Id, user.Version, model.AuditEntry{}))
+		assert.Equal(t, ErrNotFound, c.users.DeleteUser(ctx, 999, 1, model.AuditEntry{}))
+
+		page, err := c.users.GetUsers(ctx, UserQuery{})
+		require.NoError(t, err)
+		assert.Zero(t, page.Total)
+
+		restored, err := c.users.RestoreUser(ctx, user.Id, model.AuditEntry{})
+		require.NoError(t, err)
+		assert.False(t, restored.DeletedAt.Valid)
+		user, err = c.users.GetUserById(ctx, user.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "ana", user.UserName)
+	})
+}
+
+func TestConformance_WritesRecordAudit(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		user, err := c.users.InsertUser(ctx, model.User{UserName: "ana", Email: "ana@example.com"}, model.AuditEntry{Action: model.AuditActionCreate})
+		require.NoError(t, err)
+
+		stale := user
+		user.Name = "Ana"
+		require.NoError(t, c.users.UpdateUser(ctx, user, model.AuditEntry{Action: model.AuditActionUpdate, RequestId: "req-1"}))
+		assert.Equal(t, ErrStaleVersion, c.users.UpdateUser(ctx, stale, model.AuditEntry{Action: model.AuditActionUpdate, RequestId: "req-2"}))
+		// An update that changed nothing has no entry
+		require.NoError(t, c.users.UpdateUser(ctx, model.User{Id: user.Id, UserName: "ana", Email: "ana@example.com", Name: "Ana", Version: 2}, model.AuditEntry{}))
+
+		require.NoError(t, c.users.DeleteUser(ctx, user.Id, 3, model.AuditEntry{Action: model.AuditActionDelete}))
+		_, err = c.users.RestoreUser(ctx, user.Id, model.AuditEntry{Action: model.AuditActionRestore})
+		require.NoError(t, err)
+
+		page, err := c.audit.GetAuditEntries(ctx, AuditQuery{TargetId: user.Id})
+		require.NoError(t, err)
+		var actions []string
+		for _, entry := range page.Entries {
+			actions = append(actions, entry.Action)
+		}
+		// Newest first
+		assert.Equal(t, []string{model.AuditActionRestore, model.AuditActionDelete, model.AuditActionUpdate, model.AuditActionCreate}, actions)
+		assert.Equal(t, "req-1", page.Entries[2].RequestId)
+	})
+}
+
+func TestConformance_PurgeUsers(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		users := insertUsers(t, c,
+			model.User{UserName: "ana", Email: "ana@example.com"},
+			model.User{UserName: "bruno", Email: "bruno@example.com"},
+		)
+		_, err := c.refreshTokens.InsertRefreshToken(ctx, model.RefreshToken{UserId: users[0].Id, TokenHash: "refresh", FamilyId: "family", ExpiresAt: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		_, err = c.oneTimeTokens.InsertOneTimeToken(ctx, model.OneTimeToken{UserId: users[0].Id, Purpose: model.PurposePasswordReset, TokenHash: "reset", ExpiresAt: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+
+		require.NoError(t, c.users.DeleteUser(ctx, users[0].Id, users[0].Version, model.AuditEntry{}))
+
+		entry := model.AuditEntry{Action: model.AuditActionPurge, CreatedAt: time.Now()}
+		purged, err := c.users.PurgeUsers(ctx, time.Now().Add(-time.Hour), entry)
+		require.NoError(t, err)
+		assert.Zero(t, purged)
+
+		purged, err = c.users.PurgeUsers(ctx, time.Now().Add(time.Minute), entry)
+		require.NoError(t, err)
+		assert.Equal(t, 1, purged)
+
+		page, err := c.audit.GetAuditEntries(ctx, AuditQuery{Action: model.AuditActionPurge})
+		require.NoError(t, err)
+		require.Len(t, page.Entries, 1)
+		assert.Equal(t, users[0].Id, page.Entries[0].TargetId)
+
+		_, err = c.users.RestoreUser(ctx, users[0].Id, model.AuditEntry{})
+		assert.Equal(t, ErrNotFound, err)
+		taken, err := c.users.EmailTaken(ctx, "ana@example.com")
+		assert.NoError(t, err)
+		assert.False(t, taken)
+		bruno, err := c.users.GetUserById(ctx, users[1].Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "bruno", bruno.UserName)
+
+		_, err = c.refreshTokens.GetRefreshTokenByHash(ctx, "refresh")
+		assert.Equal(t, ErrNotFound, err)
+		_, err = c.oneTimeTokens.GetOneTimeTokenByHash(ctx, "reset", model.PurposePasswordReset)
+		assert.Equal(t, ErrNotFound, err)
+
+		// The email and username can be taken again
+		_, err = c.users.InsertUser(ctx, model.User{UserName: "ana", Email: "ana@example.com"}, model.AuditEntry{})
+		assert.NoError(t, err)
+	})
+}
+
+func TestConformance_GetUsers(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		insertUsers(t, c,
+			model.User{Name: "carla", UserName: "carla", Email: "carla@uni.edu", Type: true},
+			model.User{Name: "ana", UserName: "ana", Email: "ana@example.com"},
+			model.User{Name: "bruno", UserName: "bruno", Email: "bruno@UNI.edu"},
+			model.User{Name: "anabel", UserName: "anabel", Email: "anabel@example.com"},
+		)
+
+		page, err := c.users.GetUsers(ctx, UserQuery{SortBy: "name"})
+		require.NoError(t, err)
+		assert.Equal(t, 4, page.Total)
+		assert.Equal(t, []string{"ana", "anabel", "bruno", "carla"}, userNames(page.Users))
+		assert.Empty(t, page.NextCursor)
+
+		page, err = c.users.GetUsers(ctx, UserQuery{SortBy: "name", Descending: true, Offset: 1, Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"bruno", "anabel"}, userNames(page.Users))
+
+		page, err = c.users.GetUsers(ctx, UserQuery{NamePrefix: "AN"})
+		require.NoError(t, err)
+		assert.Equal(t, 2, page.Total)
+		assert.Equal(t, []string{"ana", "anabel"}, userNames(page.Users))
+
+		page, err = c.users.GetUsers(ctx, UserQuery{EmailDomain: "uni.edu"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"carla", "bruno"}, userNames(page.Users))
+
+		admin := true
+		page, err = c.users.GetUsers(ctx, UserQuery{Type: &admin})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"carla"}, userNames(page.Users))
+
+		_, err = c.users.GetUsers(ctx, UserQuery{SortBy: "password"})
+		assert.Error(t, err)
+		_, err = c.users.GetUsers(ctx, UserQuery{Cursor: "not a cursor"})
+		assert.Equal(t, ErrInvalidCursor, err)
+	})
+}
+
+func TestConformance_GetUsersCursor(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		insertUsers(t, c,
+			model.User{Name: "b", UserName: "b1", Email: "b1@example.com", Type: true},
+			model.User{Name: "a", UserName: "a1", Email: "a1@example.com"},
+			model.User{Name: "b", UserName: "b2", Email: "b2@example.com"},
+			model.User{Name: "c", UserName: "c1", Email: "c1@example.com", Type: true},
+			model.User{Name: "a", UserName: "a2", Email: "a2@example.com"},
+		)
+
+		for _, sortBy := range []string{"id", "name", "type"} {
+			for _, descending := range []bool{false, true} {
+				all, err := c.users.GetUsers(ctx, UserQuery{SortBy: sortBy, Descending: descending})
+				require.NoError(t, err)
+
+				var walked model.Users
+				query := UserQuery{SortBy: sortBy, Descending: descending, Limit: 2}
+				for {
+					page, err := c.users.GetUsers(ctx, query)
+					require.NoError(t, err)
+					walked = append(walked, page.Users...)
+					if page.NextCursor == "" {
+						break
+					}
+					query.Cursor = page.NextCursor
+				}
+				assert.Equal(t, userIds(all.Users), userIds(walked), "sort %s descending %v", sortBy, descending)
+			}
+		}
+
+		page, err := c.users.GetUsers(ctx, UserQuery{SortBy: "name", Limit: 1})
+		require.NoError(t, err)
+		_, err = c.users.GetUsers(ctx, UserQuery{SortBy: "username", Cursor: page.NextCursor})
+		assert.Equal(t, ErrInvalidCursor, err)
+	})
+}
+
+func TestConformance_RefreshTokens(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		expiresAt := time.Now().Add(time.Hour)
+		first, err := c.refreshTokens.InsertRefreshToken(ctx, model.RefreshToken{UserId: 1, TokenHash: "first", FamilyId: "family", ExpiresAt: expiresAt})
+		require.NoError(t, err)
+		second, err := c.refreshTokens.InsertRefreshToken(ctx, model.RefreshToken{UserId: 1, TokenHash: "second", FamilyId: "family", ExpiresAt: expiresAt})
+		require.NoError(t, err)
+		other, err := c.refreshTokens.InsertRefreshToken(ctx, model.RefreshToken{UserId: 2, TokenHash: "other", FamilyId: "other", ExpiresAt: expiresAt})
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Id, second.Id)
+
+		_, err = c.refreshTokens.InsertRefreshToken(ctx, model.RefreshToken{UserId: 1, TokenHash: "first", FamilyId: "family", ExpiresAt: expiresAt})
+		assert.Error(t, err)
+
+		stored, err := c.refreshTokens.GetRefreshTokenByHash(ctx, "first")
+		require.NoError(t, err)
+		assert.Equal(t, first.Id, stored.Id)
+		assert.Nil(t, stored.RevokedAt)
+		_, err = c.refreshTokens.GetRefreshTokenByHash(ctx, "unknown")
+		assert.Equal(t, ErrNotFound, err)
+
+		revoked, err := c.refreshTokens.RevokeRefreshToken(ctx, first.Id)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+		revoked, err = c.refreshTokens.RevokeRefreshToken(ctx, first.Id)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+
+		require.NoError(t, c.refreshTokens.RevokeRefreshTokenFamily(ctx, "family"))
+		stored, _ = c.refreshTokens.GetRefreshTokenByHash(ctx, "second")
+		assert.NotNil(t, stored.RevokedAt)
+		stored, _ = c.refreshTokens.GetRefreshTokenByHash(ctx, "other")
+		assert.Nil(t, stored.RevokedAt)
+
+		require.NoError(t, c.refreshTokens.RevokeUserRefreshTokens(ctx, 2))
+		stored, _ = c.refreshTokens.GetRefreshTokenByHash(ctx, "other")
+		assert.NotNil(t, stored.RevokedAt)
+		assert.Equal(t, other.Id, stored.Id)
+	})
+}
+
+func TestConformance_OneTimeTokens(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		token, err := c.oneTimeTokens.InsertOneTimeToken(ctx, model.OneTimeToken{UserId: 1, Purpose: model.PurposeEmailVerification, TokenHash: "verify", ExpiresAt: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		assert.NotZero(t, token.Id)
+
+		_, err = c.oneTimeTokens.InsertOneTimeToken(ctx, model.OneTimeToken{UserId: 1, Purpose: model.PurposePasswordReset, TokenHash: "verify", ExpiresAt: time.Now().Add(time.Hour)})
+		assert.Error(t, err)
+
+		stored, err := c.oneTimeTokens.GetOneTimeTokenByHash(ctx, "verify", model.PurposeEmailVerification)
+		require.NoError(t, err)
+		assert.Equal(t, token.Id, stored.Id)
+		_, err = c.oneTimeTokens.GetOneTimeTokenByHash(ctx, "verify", model.PurposePasswordReset)
+		assert.Equal(t, ErrNotFound, err)
+
+		used, err := c.oneTimeTokens.UseOneTimeToken(ctx, token.Id)
+		require.NoError(t, err)
+		assert.True(t, used)
+		used, err = c.oneTimeTokens.UseOneTimeToken(ctx, token.Id)
+		require.NoError(t, err)
+		assert.False(t, used)
+	})
+}
+
+func TestConformance_RevokeUserOneTimeTokens(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		for _, token := range []model.OneTimeToken{
+			{UserId: 1, Purpose: model.PurposeEmailVerification, TokenHash: "verify-1", Email: "ana@example.com"},
+			{UserId: 1, Purpose: model.PurposeEmailVerification, TokenHash: "verify-2", Email: "ana@example.com"},
+			{UserId: 1, Purpose: model.PurposePasswordReset, TokenHash: "reset-1", Email: "ana@example.com"},
+			{UserId: 2, Purpose: model.PurposeEmailVerification, TokenHash: "verify-3", Email: "bruno@example.com"},
+		} {
+			token.ExpiresAt = time.Now().Add(time.Hour)
+			_, err := c.oneTimeTokens.InsertOneTimeToken(ctx, token)
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, c.oneTimeTokens.RevokeUserOneTimeTokens(ctx, 1, model.PurposeEmailVerification))
+
+		for hash, revoked := range map[string]bool{"verify-1": true, "verify-2": true, "reset-1": false, "verify-3": false} {
+			purpose := model.PurposeEmailVerification
+			if hash == "reset-1" {
+				purpose = model.PurposePasswordReset
+			}
+			stored, err := c.oneTimeTokens.GetOneTimeTokenByHash(ctx, hash, purpose)
+			require.NoError(t, err)
+			assert.Equal(t, revoked, stored.UsedAt != nil, hash)
+			assert.NotEmpty(t, stored.Email)
+		}
+	})
+}
+
+func TestConformance_AuditEntries(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		start := time.Now().Truncate(time.Second)
+		admin := 1
+		changes := []model.AuditChange{{Field: "name", Before: json.RawMessage(`"Ana"`), After: json.RawMessage(`"Anabel"`)}}
+
+		for i, entry := range []model.AuditEntry{
+			{Action: model.AuditActionCreate, TargetId: 2},
+			{ActorId: &admin, Action: model.AuditActionUpdate, TargetId: 2, Changes: changes, RequestId: "req-1"},
+			{ActorId: &admin, Action: model.AuditActionDelete, TargetId: 3},
+		} {
+			entry.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+			stored, err := c.audit.InsertAuditEntry(ctx, entry)
+			require.NoError(t, err)
+			assert.NotZero(t, stored.Id)
+		}
+
+		page, err := c.audit.GetAuditEntries(ctx, AuditQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, 3, page.Total)
+		require.Len(t, page.Entries, 3)
+		// Newest first
+		assert.Equal(t, model.AuditActionDelete, page.Entries[0].Action)
+		assert.Nil(t, page.Entries[2].ActorId)
+
+		update := page.Entries[1]
+		assert.Equal(t, admin, *update.ActorId)
+		assert.Equal(t, "req-1", update.RequestId)
+		assert.Equal(t, changes, update.Changes)
+
+		for _, test := range []struct {
+			query  AuditQuery
+			total  int
+			action string
+		}{
+			{AuditQuery{ActorId: admin}, 2, model.AuditActionDelete},
+			{AuditQuery{TargetId: 2}, 2, model.AuditActionUpdate},
+			{AuditQuery{Action: model.AuditActionCreate}, 1, model.AuditActionCreate},
+			{AuditQuery{From: start.Add(time.Minute)}, 2, model.AuditActionDelete},
+			{AuditQuery{To: start.Add(time.Minute)}, 1, model.AuditActionCreate},
+			{AuditQuery{Limit: 1, Offset: 1}, 3, model.AuditActionUpdate},
+		} {
+			page, err := c.audit.GetAuditEntries(ctx, test.query)
+			require.NoError(t, err)
+			assert.Equal(t, test.total, page.Total, test.query)
+			assert.Equal(t, test.action, page.Entries[0].Action, test.query)
+		}
+	})
+}
+
+func TestConformance_CancelledContext(t *testing.T) {
+	runConformance(t, func(t *testing.T, c clients) {
+		user := insertUsers(t, c, model.User{UserName: "ana", Email: "ana@example.com"})[0]
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := c.users.GetUserByUsername(cancelled, "ana")
+		assert.ErrorIs(t, err, context.Canceled)
+		var transient *TransientError
+		assert.ErrorAs(t, err, &transient)
+		_, err = c.users.GetUsers(cancelled, UserQuery{})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, c.users.DeleteUser(cancelled, user.Id, user.Version, model.AuditEntry{}), context.Canceled)
+		_, err = c.users.GetUserById(cancelled, user.Id)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = c.users.InsertUser(cancelled, model.User{UserName: "bruno", Email: "bruno@example.com"}, model.AuditEntry{})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = c.refreshTokens.GetRefreshTokenByHash(cancelled, "refresh")
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = c.oneTimeTokens.UseOneTimeToken(cancelled, 1)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		// Nothing was changed
+		_, err = c.users.GetUserById(ctx, user.Id)
+		assert.NoError(t, err)
+		_, err = c.users.GetUserByUsername(ctx, "bruno")
+		assert.Equal(t, ErrNotFound, err)
+	})
+}
+
+// assertDuplicate checks that err reports a repeated value of the column.
+func assertDuplicate(t *testing.T, err error, column string) {
+	var duplicate *DuplicateKeyError
+	if assert.ErrorAs(t, err, &duplicate) {
+		assert.Equal(t, column, duplicate.Column)
+	}
+}
+
+func userIds(users model.Users) []int {
+	ids := []int{}
+	for _, user := range users {
+		ids = append(ids, user.Id)
+	}
+	return ids
+}
+
+// The gorm clients rely on the database for this, so it's only tested on memory
+func TestMemoryStore_ConcurrentInserts(t *testing.T) {
+	users := NewMemoryUserClient(NewMemoryStore())
+
+	var wg sync.WaitGroup
+	ids := make(chan int, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, _ := users.InsertUser(ctx, model.User{UserName: "ana", Email: "ana@example.com"}, model.AuditEntry{})
+			ids <- user.Id
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	inserted := 0
+	for id := range ids {
+		if id != 0 {
+			inserted++
+		}
+	}
+	assert.Equal(t, 1, inserted)
+
+	all, err := users.GetAllUsers(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+}
@@ -0,0 +1,177 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when the record asked for doesn't exist.
+var ErrNotFound = errors.New("record not found")
+
+// ErrStaleVersion is returned when a write carries a version of the record that is
+// no longer the stored one, as someone else changed it since it was read.
+var ErrStaleVersion = errors.New("stale record version")
+
+// DuplicateKeyError is returned when a write would repeat the value of a unique
+// column.
+type DuplicateKeyError struct {
+	Table  string
+	Column string
+	Err    error
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate value for %s.%s", e.Table, e.Column)
+}
+
+func (e *DuplicateKeyError) Unwrap() error {
+	return e.Err
+}
+
+// TransientError is returned when the database couldn't serve the request but may
+// on a retry: the connection was lost, the database is busy or the request ran out
+// of time.
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string {
+	return "database unavailable: " + e.Err.Error()
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// Key (email)=(jdoe@example.com) already exists.
+	postgresKeyDetail = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+	// Duplicate entry 'jdoe@example.com' for key 'users.email'
+	mysqlDuplicateKey = regexp.MustCompile(`for key '([^']+)'$`)
+)
+
+// translateError turns the errors of gorm and the database drivers into the errors
+// of this package. Errors that don't fit any of them are returned as they are.
+func translateError(table string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if column, ok := duplicateColumn(table, err); ok {
+		return &DuplicateKeyError{Table: table, Column: column, Err: err}
+	}
+	if isTransient(err) {
+		return &TransientError{Err: err}
+	}
+	return err
+}
+
+// duplicateColumn tells whether err is a unique constraint violation of table and
+// which column caused it.
+func duplicateColumn(table string, err error) (string, bool) {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		if sqliteErr.ExtendedCode != sqlite3.ErrConstraintUnique && sqliteErr.ExtendedCode != sqlite3.ErrConstraintPrimaryKey {
+			return "", false
+		}
+		// UNIQUE constraint failed: users.email
+		_, columns, _ := strings.Cut(sqliteErr.Error(), ": ")
+		column, _, _ := strings.Cut(columns, ", ")
+		return afterDot(column), true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		if mysqlErr.Number != 1062 {
+			return "", false
+		}
+		// MySQL names the key, which is the constraint gorm created for the column
+		if match := mysqlDuplicateKey.FindStringSubmatch(mysqlErr.Message); match != nil {
+			return constraintColumn(table, afterDot(match[1])), true
+		}
+		return "", true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code != "23505" {
+			return "", false
+		}
+		if match := postgresKeyDetail.FindStringSubmatch(pgErr.Detail); match != nil {
+			return match[1], true
+		}
+		return constraintColumn(table, pgErr.ConstraintName), true
+	}
+
+	return "", false
+}
+
+func afterDot(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// constraintColumn returns the column of a single column constraint of table from
+// its name. gorm names unique constraints uni_<table>_<column> and indexes
+// idx_<table>_<column>; other names are taken to be the column itself, as with
+// the indexes of tables created before gorm named them.
+func constraintColumn(table string, name string) string {
+	for _, prefix := range []string{"uni_", "idx_"} {
+		if column, ok := strings.CutPrefix(name, prefix+table+"_"); ok {
+			return column
+		}
+	}
+	return name
+}
+
+// isTransient tells whether err comes from the database being unreachable, busy or
+// too slow for the request, rather than from the request itself.
+func isTransient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) ||
+		errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1040, 1205, 1213: // Too many connections, lock wait timeout, deadlock
+			return true
+		}
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Connection exceptions, insufficient resources, operator intervention,
+		// serialization failures and deadlocks
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "53") ||
+			strings.HasPrefix(pgErr.Code, "57P") || pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &connectErr) || pgconn.Timeout(err)
+}
@@ -0,0 +1,79 @@
+package user
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTranslateError(t *testing.T) {
+	assert.Nil(t, translateError("users", nil))
+	assert.Equal(t, ErrNotFound, translateError("users", gorm.ErrRecordNotFound))
+
+	other := errors.New("syntax error")
+	assert.Equal(t, other, translateError("users", other))
+}
+
+func TestTranslateError_DuplicateKey(t *testing.T) {
+	tests := map[string]struct {
+		err    error
+		column string
+	}{
+		"mysql": {
+			err:    &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'ana@example.com' for key 'users.email'"},
+			column: "email",
+		},
+		"mysql constraint": {
+			err:    &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'ana' for key 'users.uni_users_user_name'"},
+			column: "user_name",
+		},
+		"mysql index": {
+			err:    &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'ana@example.com' for key 'idx_users_email'"},
+			column: "email",
+		},
+		"postgres": {
+			err:    &pgconn.PgError{Code: "23505", ConstraintName: "uni_users_user_name", Detail: "Key (user_name)=(ana) already exists."},
+			column: "user_name",
+		},
+		"postgres without detail": {
+			err:    &pgconn.PgError{Code: "23505", ConstraintName: "uni_users_email"},
+			column: "email",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := translateError("users", fmt.Errorf("wrapped: %w", test.err))
+
+			var duplicate *DuplicateKeyError
+			if assert.ErrorAs(t, err, &duplicate) {
+				assert.Equal(t, "users", duplicate.Table)
+				assert.Equal(t, test.column, duplicate.Column)
+			}
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+}
+
+func TestTranslateError_Transient(t *testing.T) {
+	for _, err := range []error{
+		context.DeadlineExceeded,
+		driver.ErrBadConn,
+		mysql.ErrInvalidConn,
+		&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"},
+		&pgconn.PgError{Code: "57P01", Message: "terminating connection due to administrator command"},
+	} {
+		var transient *TransientError
+		assert.ErrorAs(t, translateError("users", err), &transient, err.Error())
+	}
+
+	var transient *TransientError
+	assert.False(t, errors.As(translateError("users", &pgconn.PgError{Code: "42601"}), &transient))
+}
@@ -0,0 +1,81 @@
+package user
+
+import (
+	"context"
+	"sort"
+	"user-api/model"
+)
+
+// MemoryAuditClient implements AuditClientInterface on a MemoryStore.
+type MemoryAuditClient struct {
+	store *MemoryStore
+}
+
+func NewMemoryAuditClient(store *MemoryStore) *MemoryAuditClient {
+	return &MemoryAuditClient{store: store}
+}
+
+func (c *MemoryAuditClient) InsertAuditEntry(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return entry, &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	c.store.lastAuditEntryId++
+	entry.Id = c.store.lastAuditEntryId
+	entry.Changes = append([]model.AuditChange{}, entry.Changes...)
+	c.store.auditEntries = append(c.store.auditEntries, entry)
+	return entry, nil
+}
+
+func (c *MemoryAuditClient) GetAuditEntries(ctx context.Context, query AuditQuery) (AuditPage, error) {
+	if err := ctx.Err(); err != nil {
+		return AuditPage{}, &TransientError{Err: err}
+	}
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+
+	if query.Limit <= 0 {
+		query.Limit = DefaultPageSize
+	}
+
+	var matches []model.AuditEntry
+	for _, entry := range c.store.auditEntries {
+		if matchesAuditQuery(entry, query) {
+			matches = append(matches, entry)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.After(matches[j].CreatedAt)
+		}
+		return matches[i].Id > matches[j].Id
+	})
+
+	page := AuditPage{Total: len(matches)}
+	if query.Offset < len(matches) {
+		end := min(query.Offset+query.Limit, len(matches))
+		page.Entries = matches[query.Offset:end]
+	}
+	return page, nil
+}
+
+func matchesAuditQuery(entry model.AuditEntry, query AuditQuery) bool {
+	if query.ActorId != 0 && (entry.ActorId == nil || *entry.ActorId != query.ActorId) {
+		return false
+	}
+	if query.TargetId != 0 && entry.TargetId != query.TargetId {
+		return false
+	}
+	if query.Action != "" && entry.Action != query.Action {
+		return false
+	}
+	if !query.From.IsZero() && entry.CreatedAt.Before(query.From) {
+		return false
+	}
+	if !query.To.IsZero() && !entry.CreatedAt.Before(query.To) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,477 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"user-api/model"
+	"user-api/search"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// MemoryStore keeps users and tokens in process memory, in place of a database. It
+// is meant for tests and demo mode: nothing survives a restart. The memory clients
+// built on the same store share its data, like the gorm clients share a database.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	users         map[int]model.User
+	refreshTokens map[int]model.RefreshToken
+	oneTimeTokens map[int]model.OneTimeToken
+	auditEntries  []model.AuditEntry
+
+	// Last id handed out per table. Ids are never reused, even after a purge
+	lastUserId         int
+	lastRefreshTokenId int
+	lastOneTimeTokenId int
+	lastAuditEntryId   int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:         map[int]model.User{},
+		refreshTokens: map[int]model.RefreshToken{},
+		oneTimeTokens: map[int]model.OneTimeToken{},
+	}
+}
+
+// record adds entry to the audit log as a change to the user with the given id,
+// like recordAudit does in the transaction of the gorm clients. The caller must
+// hold the lock.
+func (s *MemoryStore) record(entry model.AuditEntry, targetId int) {
+	if entry.Action == "" {
+		return
+	}
+	s.lastAuditEntryId++
+	entry.Id = s.lastAuditEntryId
+	entry.TargetId = targetId
+	entry.Changes = append([]model.AuditChange{}, entry.Changes...)
+	s.auditEntries = append(s.auditEntries, entry)
+}
+
+// duplicateError mirrors the unique constraint violation a database would report.
+func duplicateError(table string, column string) error {
+	return &DuplicateKeyError{Table: table, Column: column}
+}
+
+// MemoryUserClient implements UserClientInterface on a MemoryStore, with the same
+// semantics as UserClient: unique emails and usernames (deleted users included),
+// soft deletes and the same errors.
+type MemoryUserClient struct {
+	store *MemoryStore
+}
+
+func NewMemoryUserClient(store *MemoryStore) *MemoryUserClient {
+	return &MemoryUserClient{store: store}
+}
+
+// findLive returns the first user, by id, that matches and isn't deleted. The
+// caller must hold the lock.
+func (c *MemoryUserClient) findLive(match func(user model.User) bool) (model.User, error) {
+	for _, user := range c.sortedUsers() {
+		if !user.DeletedAt.Valid && match(user) {
+			return user, nil
+		}
+	}
+	return model.User{}, ErrNotFound
+}
+
+// sortedUsers returns every user, deleted ones included, ordered by id. The caller
+// must hold the lock.
+func (c *MemoryUserClient) sortedUsers() model.Users {
+	users := make(model.Users, 0, len(c.store.users))
+	for _, user := range c.store.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Id < users[j].Id })
+	return users
+}
+
+// checkUnique fails if another user, deleted or not, has the email or username.
+// The caller must hold the lock.
+func (c *MemoryUserClient) checkUnique(user model.User) error {
+	for _, other := range c.store.users {
+		if other.Id == user.Id {
+			continue
+		}
+		if other.Email == user.Email {
+			return duplicateError("users", "email")
+		}
+		if other.UserName == user.UserName {
+			return duplicateError("users", "user_name")
+		}
+	}
+	return nil
+}
+
+func (c *MemoryUserClient) GetUserById(ctx context.Context, id int) (model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return model.User{}, &TransientError{Err: err}
+	}
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+
+	return c.findLive(func(user model.User) bool { return user.Id == id })
+}
+
+func (c *MemoryUserClient) GetUserByUsername(ctx context.Context, username string) (model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return model.User{}, &TransientError{Err: err}
+	}
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+
+	return c.findLive(func(user model.User) bool { return user.UserName == username })
+}
+
+func (c *MemoryUserClient) FindUserByEmail(ctx context.Context, email string) (model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return model.User{}, &TransientError{Err: err}
+	}
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+
+	return c.findLive(func(user model.User) bool { return user.Email == email })
+}
+
+// EmailTaken tells whether the email is taken, by a deleted user too.
+func (c *MemoryUserClient) EmailTaken(ctx context.Context, email string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, &TransientError{Err: err}
+	}
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+
+	for _, user := range c.store.users {
+		if user.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetAllUsers returns every user that isn't deleted, to fill an in-process search
+// index.
+func (c *MemoryUserClient) GetAllUsers(ctx context.Context) (model.Users, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &TransientError{Err: err}
+	}
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+
+	users := model.Users{}
+	for _, user := range c.sortedUsers() {
+		if !user.DeletedAt.Valid {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// InsertUser stores the user with a new id.
+func (c *MemoryUserClient) InsertUser(ctx context.Context, user model.User, entry model.AuditEntry) (model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return user, &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	if err := c.insert(&user); err != nil {
+		log.Error("Error inserting user: ", err)
+		return user, err
+	}
+	c.store.record(entry, user.Id)
+	log.Debug("User Created: ", user.Id)
+	return user, nil
+}
+
+// insert assigns the next id unless the user already has one. The caller must
+// hold the lock.
+func (c *MemoryUserClient) insert(user *model.User) error {
+	if user.Id != 0 {
+		if _, taken := c.store.users[user.Id]; taken {
+			return duplicateError("users", "id")
+		}
+	}
+	user.SearchText = search.NewDocument(*user).Text()
+	if err := c.checkUnique(*user); err != nil {
+		return err
+	}
+
+	if user.Id == 0 {
+		user.Id = c.store.lastUserId + 1
+	}
+	user.Version = 1
+	if user.Id > c.store.lastUserId {
+		c.store.lastUserId = user.Id
+	}
+	c.store.users[user.Id] = *user
+	return nil
+}
+
+func (c *MemoryUserClient) DeleteUser(ctx context.Context, id int, version int, entry model.AuditEntry) error {
+	if err := ctx.Err(); err != nil {
+		return &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	user, err := c.findLive(func(user model.User) bool { return user.Id == id })
+	if err != nil {
+		log.Warn("User not found for deletion, ID: ", id)
+		return err
+	}
+	if user.Version != version {
+		log.Warn("Stale version deleting user, ID: ", id)
+		return ErrStaleVersion
+	}
+
+	user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	c.store.users[id] = user
+	c.store.record(entry, id)
+
+	log.Info("User deleted successfully, ID: ", id)
+	return nil
+}
+
+// UpdateUser saves every field of the user if its version is still the stored one.
+// It returns ErrNotFound if the user doesn't exist or is deleted.
+func (c *MemoryUserClient) UpdateUser(ctx context.Context, user model.User, entry model.AuditEntry) error {
+	if err := ctx.Err(); err != nil {
+		return &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	stored, exists := c.store.users[user.Id]
+	if !exists || stored.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	if stored.Version != user.Version {
+		log.Warn("Stale version updating user, ID: ", user.Id)
+		return ErrStaleVersion
+	}
+
+	user.Version++
+	user.DeletedAt = stored.DeletedAt
+	user.SearchText = search.NewDocument(user).Text()
+	if err := c.checkUnique(user); err != nil {
+		return err
+	}
+	c.store.users[user.Id] = user
+	c.store.record(entry, user.Id)
+	return nil
+}
+
+// RestoreUser clears the deletion mark of a deleted user. It returns
+// ErrNotFound if there is no deleted user with that id.
+func (c *MemoryUserClient) RestoreUser(ctx context.Context, id int, entry model.AuditEntry) (model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return model.User{}, &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	user, exists := c.store.users[id]
+	if !exists || !user.DeletedAt.Valid {
+		return model.User{}, ErrNotFound
+	}
+
+	user.DeletedAt = gorm.DeletedAt{}
+	user.Version++
+	c.store.users[id] = user
+	c.store.record(entry, id)
+
+	log.Info("User restored successfully, ID: ", id)
+	return user, nil
+}
+
+// PurgeUsers permanently removes the users deleted before deletedBefore, together
+// with their tokens, and returns how many users were removed. A copy of entry is
+// added to the audit log for each of them.
+func (c *MemoryUserClient) PurgeUsers(ctx context.Context, deletedBefore time.Time, entry model.AuditEntry) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	purged := map[int]bool{}
+	ids := []int{}
+	for id, user := range c.store.users {
+		if user.DeletedAt.Valid && user.DeletedAt.Time.Before(deletedBefore) {
+			purged[id] = true
+			ids = append(ids, id)
+			delete(c.store.users, id)
+		}
+	}
+	if len(purged) == 0 {
+		return 0, nil
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		c.store.record(entry, id)
+	}
+
+	for id, token := range c.store.refreshTokens {
+		if purged[token.UserId] {
+			delete(c.store.refreshTokens, id)
+		}
+	}
+	for id, token := range c.store.oneTimeTokens {
+		if purged[token.UserId] {
+			delete(c.store.oneTimeTokens, id)
+		}
+	}
+
+	log.Info("Users purged: ", len(purged))
+	return len(purged), nil
+}
+
+func (c *MemoryUserClient) GetUsers(ctx context.Context, query UserQuery) (UserPage, error) {
+	if err := ctx.Err(); err != nil {
+		return UserPage{}, &TransientError{Err: err}
+	}
+	var page UserPage
+
+	if query.Limit <= 0 {
+		query.Limit = DefaultPageSize
+	}
+	if query.SortBy == "" {
+		query.SortBy = "id"
+	}
+	if _, ok := SortableColumns[query.SortBy]; !ok {
+		return page, errors.New("unknown sort field " + query.SortBy)
+	}
+
+	var after *cursor
+	if query.Cursor != "" {
+		decoded, err := decodeCursor(query.Cursor)
+		if err != nil || decoded.SortBy != query.SortBy || decoded.Descending != query.Descending {
+			return page, ErrInvalidCursor
+		}
+		after = &decoded
+	}
+
+	c.store.mu.RLock()
+	all := c.sortedUsers()
+	c.store.mu.RUnlock()
+
+	users := model.Users{}
+	for _, user := range all {
+		if !user.DeletedAt.Valid && matchesQuery(user, query) {
+			users = append(users, user)
+		}
+	}
+	page.Total = len(users)
+
+	// order is negative when a goes before b in the requested order
+	order := func(aValue interface{}, aId int, bValue interface{}, bId int) (int, bool) {
+		result := 0
+		if query.SortBy != "id" {
+			var ok bool
+			if result, ok = compareValues(aValue, bValue); !ok {
+				return 0, false
+			}
+		}
+		if result == 0 {
+			result = aId - bId
+		}
+		if query.Descending {
+			result = -result
+		}
+		return result, true
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		result, _ := order(sortValue(users[i], query.SortBy), users[i].Id, sortValue(users[j], query.SortBy), users[j].Id)
+		return result < 0
+	})
+
+	if after != nil {
+		// Keyset pagination: rows after (value, id) in the requested order
+		rest := model.Users{}
+		for _, user := range users {
+			result, ok := order(sortValue(user, query.SortBy), user.Id, after.Value, after.Id)
+			if !ok {
+				return page, ErrInvalidCursor
+			}
+			if result > 0 {
+				rest = append(rest, user)
+			}
+		}
+		users = rest
+	} else if query.Offset > 0 {
+		if query.Offset >= len(users) {
+			users = model.Users{}
+		} else {
+			users = users[query.Offset:]
+		}
+	}
+
+	if len(users) > query.Limit {
+		users = users[:query.Limit]
+		last := users[len(users)-1]
+		page.NextCursor = cursor{SortBy: query.SortBy, Descending: query.Descending, Value: sortValue(last, query.SortBy), Id: last.Id}.encode()
+	}
+	page.Users = users
+
+	return page, nil
+}
+
+// matchesQuery applies the filters of the query like filterUsers does in SQL.
+func matchesQuery(user model.User, query UserQuery) bool {
+	if query.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(user.Name), strings.ToLower(query.NamePrefix)) {
+		return false
+	}
+	if query.EmailDomain != "" && !strings.HasSuffix(strings.ToLower(user.Email), "@"+strings.ToLower(query.EmailDomain)) {
+		return false
+	}
+	if query.Type != nil && user.Type != *query.Type {
+		return false
+	}
+	return true
+}
+
+// compareValues compares two sort values. A cursor decoded from JSON holds numbers
+// as float64, so numbers and booleans are compared as float64. It returns false
+// when the values can't be compared.
+func compareValues(a, b interface{}) (int, bool) {
+	if x, ok := a.(string); ok {
+		y, ok := b.(string)
+		return strings.Compare(x, y), ok
+	}
+
+	x, okA := number(a)
+	y, okB := number(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+	return 0, true
+}
+
+func number(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case int:
+		return float64(value), true
+	case float64:
+		return value, true
+	case bool:
+		if value {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,200 @@
+package user
+
+import (
+	"context"
+	"time"
+	"user-api/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MemoryRefreshTokenClient implements RefreshTokenClientInterface on a MemoryStore.
+type MemoryRefreshTokenClient struct {
+	store *MemoryStore
+}
+
+func NewMemoryRefreshTokenClient(store *MemoryStore) *MemoryRefreshTokenClient {
+	return &MemoryRefreshTokenClient{store: store}
+}
+
+func (c *MemoryRefreshTokenClient) InsertRefreshToken(ctx context.Context, token model.RefreshToken) (model.RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return token, &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	for _, other := range c.store.refreshTokens {
+		if other.TokenHash == token.TokenHash {
+			err := duplicateError("refresh_tokens", "token_hash")
+			log.Error("Error inserting refresh token: ", err)
+			return token, err
+		}
+	}
+
+	c.store.lastRefreshTokenId++
+	token.Id = c.store.lastRefreshTokenId
+	token.CreatedAt = time.Now()
+	c.store.refreshTokens[token.Id] = token
+
+	log.Debug("Refresh token created: ", token.Id)
+	return token, nil
+}
+
+func (c *MemoryRefreshTokenClient) GetRefreshTokenByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return model.RefreshToken{}, &TransientError{Err: err}
+	}
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+
+	for _, token := range c.store.refreshTokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return model.RefreshToken{}, ErrNotFound
+}
+
+// RevokeRefreshToken marks the token as revoked. It returns false if the token
+// was already revoked, which lets the caller detect concurrent reuse.
+func (c *MemoryRefreshTokenClient) RevokeRefreshToken(ctx context.Context, id int) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	return c.revoke(func(token model.RefreshToken) bool { return token.Id == id }) == 1, nil
+}
+
+func (c *MemoryRefreshTokenClient) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	if err := ctx.Err(); err != nil {
+		return &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	c.revoke(func(token model.RefreshToken) bool { return token.FamilyId == familyId })
+
+	log.Info("Refresh token family revoked: ", familyId)
+	return nil
+}
+
+func (c *MemoryRefreshTokenClient) RevokeUserRefreshTokens(ctx context.Context, userId int) error {
+	if err := ctx.Err(); err != nil {
+		return &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	c.revoke(func(token model.RefreshToken) bool { return token.UserId == userId })
+
+	log.Info("Refresh tokens revoked for user: ", userId)
+	return nil
+}
+
+// revoke marks the matching tokens that aren't revoked yet and returns how many
+// it marked. The caller must hold the lock.
+func (c *MemoryRefreshTokenClient) revoke(match func(token model.RefreshToken) bool) int {
+	revoked := 0
+	now := time.Now()
+	for id, token := range c.store.refreshTokens {
+		if token.RevokedAt == nil && match(token) {
+			revokedAt := now
+			token.RevokedAt = &revokedAt
+			c.store.refreshTokens[id] = token
+			revoked++
+		}
+	}
+	return revoked
+}
+
+// MemoryOneTimeTokenClient implements OneTimeTokenClientInterface on a MemoryStore.
+type MemoryOneTimeTokenClient struct {
+	store *MemoryStore
+}
+
+func NewMemoryOneTimeTokenClient(store *MemoryStore) *MemoryOneTimeTokenClient {
+	return &MemoryOneTimeTokenClient{store: store}
+}
+
+func (c *MemoryOneTimeTokenClient) InsertOneTimeToken(ctx context.Context, token model.OneTimeToken) (model.OneTimeToken, error) {
+	if err := ctx.Err(); err != nil {
+		return token, &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	for _, other := range c.store.oneTimeTokens {
+		if other.TokenHash == token.TokenHash {
+			err := duplicateError("one_time_tokens", "token_hash")
+			log.Error("Error inserting one-time token: ", err)
+			return token, err
+		}
+	}
+
+	c.store.lastOneTimeTokenId++
+	token.Id = c.store.lastOneTimeTokenId
+	token.CreatedAt = time.Now()
+	c.store.oneTimeTokens[token.Id] = token
+
+	log.Debug("One-time token created: ", token.Id)
+	return token, nil
+}
+
+func (c *MemoryOneTimeTokenClient) GetOneTimeTokenByHash(ctx context.Context, hash string, purpose string) (model.OneTimeToken, error) {
+	if err := ctx.Err(); err != nil {
+		return model.OneTimeToken{}, &TransientError{Err: err}
+	}
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+
+	for _, token := range c.store.oneTimeTokens {
+		if token.TokenHash == hash && token.Purpose == purpose {
+			return token, nil
+		}
+	}
+	return model.OneTimeToken{}, ErrNotFound
+}
+
+// UseOneTimeToken marks the token as used. It returns false if it had already
+// been used, so a token can never be redeemed twice.
+func (c *MemoryOneTimeTokenClient) UseOneTimeToken(ctx context.Context, id int) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	token, exists := c.store.oneTimeTokens[id]
+	if !exists || token.UsedAt != nil {
+		return false, nil
+	}
+
+	usedAt := time.Now()
+	token.UsedAt = &usedAt
+	c.store.oneTimeTokens[id] = token
+	return true, nil
+}
+
+// RevokeUserOneTimeTokens marks the unused tokens of the user for the purpose as used,
+// so none of them can be redeemed anymore.
+func (c *MemoryOneTimeTokenClient) RevokeUserOneTimeTokens(ctx context.Context, userId int, purpose string) error {
+	if err := ctx.Err(); err != nil {
+		return &TransientError{Err: err}
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	usedAt := time.Now()
+	for id, token := range c.store.oneTimeTokens {
+		if token.UserId == userId && token.Purpose == purpose && token.UsedAt == nil {
+			token.UsedAt = &usedAt
+			c.store.oneTimeTokens[id] = token
+		}
+	}
+
+	log.Debug("One-time tokens revoked for user: ", userId)
+	return nil
+}
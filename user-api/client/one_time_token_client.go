@@ -0,0 +1,79 @@
+package user
+
+import (
+	"context"
+	"time"
+	"user-api/model"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// OneTimeTokenClientInterface defines the interface for one-time token operations.
+type OneTimeTokenClientInterface interface {
+	InsertOneTimeToken(ctx context.Context, token model.OneTimeToken) (model.OneTimeToken, error)
+	GetOneTimeTokenByHash(ctx context.Context, hash string, purpose string) (model.OneTimeToken, error)
+	UseOneTimeToken(ctx context.Context, id int) (bool, error)
+	RevokeUserOneTimeTokens(ctx context.Context, userId int, purpose string) error
+}
+
+type OneTimeTokenClient struct {
+	db *gorm.DB
+}
+
+func NewOneTimeTokenClient(db *gorm.DB) *OneTimeTokenClient {
+	return &OneTimeTokenClient{db: db}
+}
+
+func (c *OneTimeTokenClient) InsertOneTimeToken(ctx context.Context, token model.OneTimeToken) (model.OneTimeToken, error) {
+	result := c.db.WithContext(ctx).Create(&token)
+
+	if result.Error != nil {
+		log.Error("Error inserting one-time token: ", result.Error)
+		return token, translateError("one_time_tokens", result.Error)
+	}
+	log.Debug("One-time token created: ", token.Id)
+	return token, nil
+}
+
+func (c *OneTimeTokenClient) GetOneTimeTokenByHash(ctx context.Context, hash string, purpose string) (model.OneTimeToken, error) {
+	var token model.OneTimeToken
+	result := c.db.WithContext(ctx).Where("token_hash = ? AND purpose = ?", hash, purpose).First(&token)
+
+	if result.Error != nil {
+		return token, translateError("one_time_tokens", result.Error)
+	}
+
+	return token, nil
+}
+
+// UseOneTimeToken marks the token as used. It returns false if it had already
+// been used, so a token can never be redeemed twice.
+func (c *OneTimeTokenClient) UseOneTimeToken(ctx context.Context, id int) (bool, error) {
+	result := c.db.WithContext(ctx).Model(&model.OneTimeToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+
+	if result.Error != nil {
+		log.Error("Error using one-time token: ", result.Error)
+		return false, translateError("one_time_tokens", result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeUserOneTimeTokens marks the unused tokens of the user for the purpose as used,
+// so none of them can be redeemed anymore.
+func (c *OneTimeTokenClient) RevokeUserOneTimeTokens(ctx context.Context, userId int, purpose string) error {
+	result := c.db.WithContext(ctx).Model(&model.OneTimeToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userId, purpose).
+		Update("used_at", time.Now())
+
+	if result.Error != nil {
+		log.Error("Error revoking one-time tokens: ", result.Error)
+		return translateError("one_time_tokens", result.Error)
+	}
+
+	log.Debug("One-time tokens revoked for user: ", userId)
+	return nil
+}
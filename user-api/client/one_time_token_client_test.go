@@ -0,0 +1,47 @@
+package user
+
+import (
+	"testing"
+	"time"
+	"user-api/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOneTimeTokenByHash(t *testing.T) {
+	db := setupTestDB(t)
+	oneTimeTokenClient := NewOneTimeTokenClient(db)
+
+	// Create a new one-time token
+	testToken := model.OneTimeToken{UserId: 1, Purpose: model.PurposePasswordReset, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+	insertedToken, err := oneTimeTokenClient.InsertOneTimeToken(ctx, testToken)
+	assert.NoError(t, err)
+
+	// Test case: hash and purpose match
+	retrievedToken, err := oneTimeTokenClient.GetOneTimeTokenByHash(ctx, "hash", model.PurposePasswordReset)
+	assert.NoError(t, err)
+	assert.Equal(t, insertedToken.Id, retrievedToken.Id)
+
+	// Test case: same hash for another purpose
+	_, err = oneTimeTokenClient.GetOneTimeTokenByHash(ctx, "hash", "other_purpose")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestUseOneTimeToken(t *testing.T) {
+	db := setupTestDB(t)
+	oneTimeTokenClient := NewOneTimeTokenClient(db)
+
+	// Seed the database
+	testToken := model.OneTimeToken{UserId: 1, Purpose: model.PurposePasswordReset, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+	db.Create(&testToken)
+
+	// Test case: first use
+	used, err := oneTimeTokenClient.UseOneTimeToken(ctx, testToken.Id)
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	// Test case: second use
+	used, err = oneTimeTokenClient.UseOneTimeToken(ctx, testToken.Id)
+	assert.NoError(t, err)
+	assert.False(t, used)
+}
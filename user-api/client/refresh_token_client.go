@@ -0,0 +1,92 @@
+package user
+
+import (
+	"context"
+	"time"
+	"user-api/model"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// RefreshTokenClientInterface defines the interface for refresh token operations.
+type RefreshTokenClientInterface interface {
+	InsertRefreshToken(ctx context.Context, token model.RefreshToken) (model.RefreshToken, error)
+	GetRefreshTokenByHash(ctx context.Context, hash string) (model.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id int) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyId string) error
+	RevokeUserRefreshTokens(ctx context.Context, userId int) error
+}
+
+type RefreshTokenClient struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenClient(db *gorm.DB) *RefreshTokenClient {
+	return &RefreshTokenClient{db: db}
+}
+
+func (c *RefreshTokenClient) InsertRefreshToken(ctx context.Context, token model.RefreshToken) (model.RefreshToken, error) {
+	result := c.db.WithContext(ctx).Create(&token)
+
+	if result.Error != nil {
+		log.Error("Error inserting refresh token: ", result.Error)
+		return token, translateError("refresh_tokens", result.Error)
+	}
+	log.Debug("Refresh token created: ", token.Id)
+	return token, nil
+}
+
+func (c *RefreshTokenClient) GetRefreshTokenByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+	var token model.RefreshToken
+	result := c.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token)
+
+	if result.Error != nil {
+		return token, translateError("refresh_tokens", result.Error)
+	}
+
+	return token, nil
+}
+
+// RevokeRefreshToken marks the token as revoked. It returns false if the token
+// was already revoked, which lets the caller detect concurrent reuse.
+func (c *RefreshTokenClient) RevokeRefreshToken(ctx context.Context, id int) (bool, error) {
+	result := c.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+
+	if result.Error != nil {
+		log.Error("Error revoking refresh token: ", result.Error)
+		return false, translateError("refresh_tokens", result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (c *RefreshTokenClient) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	result := c.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now())
+
+	if result.Error != nil {
+		log.Error("Error revoking refresh token family: ", result.Error)
+		return translateError("refresh_tokens", result.Error)
+	}
+
+	log.Info("Refresh token family revoked: ", familyId)
+	return nil
+}
+
+func (c *RefreshTokenClient) RevokeUserRefreshTokens(ctx context.Context, userId int) error {
+	result := c.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now())
+
+	if result.Error != nil {
+		log.Error("Error revoking refresh tokens of user: ", result.Error)
+		return translateError("refresh_tokens", result.Error)
+	}
+
+	log.Info("Refresh tokens revoked for user: ", userId)
+	return nil
+}
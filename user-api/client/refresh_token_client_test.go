@@ -0,0 +1,93 @@
+package user
+
+import (
+	"testing"
+	"time"
+	"user-api/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertAndGetRefreshTokenByHash(t *testing.T) {
+	db := setupTestDB(t)
+	refreshTokenClient := NewRefreshTokenClient(db)
+
+	// Create a new refresh token
+	testToken := model.RefreshToken{UserId: 1, TokenHash: "hash", FamilyId: "family", ExpiresAt: time.Now().Add(time.Hour)}
+	insertedToken, err := refreshTokenClient.InsertRefreshToken(ctx, testToken)
+	assert.NoError(t, err)
+	assert.NotZero(t, insertedToken.Id)
+
+	// Test case: hash exists
+	retrievedToken, err := refreshTokenClient.GetRefreshTokenByHash(ctx, "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, insertedToken.Id, retrievedToken.Id)
+	assert.Nil(t, retrievedToken.RevokedAt)
+
+	// Test case: hash does not exist
+	_, err = refreshTokenClient.GetRefreshTokenByHash(ctx, "unknown")
+	assert.Equal(t, ErrNotFound, err)
+
+	// Test case: duplicated hash
+	_, err = refreshTokenClient.InsertRefreshToken(ctx, testToken)
+	assert.Error(t, err)
+}
+
+func TestRevokeRefreshToken(t *testing.T) {
+	db := setupTestDB(t)
+	refreshTokenClient := NewRefreshTokenClient(db)
+
+	// Seed the database
+	testToken := model.RefreshToken{UserId: 1, TokenHash: "hash", FamilyId: "family", ExpiresAt: time.Now().Add(time.Hour)}
+	db.Create(&testToken)
+
+	// Test case: first revocation
+	revoked, err := refreshTokenClient.RevokeRefreshToken(ctx, testToken.Id)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	retrievedToken, _ := refreshTokenClient.GetRefreshTokenByHash(ctx, "hash")
+	assert.NotNil(t, retrievedToken.RevokedAt)
+
+	// Test case: token already revoked
+	revoked, err = refreshTokenClient.RevokeRefreshToken(ctx, testToken.Id)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestRevokeRefreshTokenFamily(t *testing.T) {
+	db := setupTestDB(t)
+	refreshTokenClient := NewRefreshTokenClient(db)
+
+	// Seed the database
+	db.Create(&model.RefreshToken{UserId: 1, TokenHash: "first", FamilyId: "family", ExpiresAt: time.Now().Add(time.Hour)})
+	db.Create(&model.RefreshToken{UserId: 1, TokenHash: "second", FamilyId: "family", ExpiresAt: time.Now().Add(time.Hour)})
+	db.Create(&model.RefreshToken{UserId: 1, TokenHash: "other", FamilyId: "other", ExpiresAt: time.Now().Add(time.Hour)})
+
+	err := refreshTokenClient.RevokeRefreshTokenFamily(ctx, "family")
+	assert.NoError(t, err)
+
+	first, _ := refreshTokenClient.GetRefreshTokenByHash(ctx, "first")
+	second, _ := refreshTokenClient.GetRefreshTokenByHash(ctx, "second")
+	other, _ := refreshTokenClient.GetRefreshTokenByHash(ctx, "other")
+	assert.NotNil(t, first.RevokedAt)
+	assert.NotNil(t, second.RevokedAt)
+	assert.Nil(t, other.RevokedAt)
+}
+
+func TestRevokeUserRefreshTokens(t *testing.T) {
+	db := setupTestDB(t)
+	refreshTokenClient := NewRefreshTokenClient(db)
+
+	// Seed the database
+	db.Create(&model.RefreshToken{UserId: 1, TokenHash: "first", FamilyId: "first", ExpiresAt: time.Now().Add(time.Hour)})
+	db.Create(&model.RefreshToken{UserId: 2, TokenHash: "other", FamilyId: "other", ExpiresAt: time.Now().Add(time.Hour)})
+
+	err := refreshTokenClient.RevokeUserRefreshTokens(ctx, 1)
+	assert.NoError(t, err)
+
+	first, _ := refreshTokenClient.GetRefreshTokenByHash(ctx, "first")
+	other, _ := refreshTokenClient.GetRefreshTokenByHash(ctx, "other")
+	assert.NotNil(t, first.RevokedAt)
+	assert.Nil(t, other.RevokedAt)
+}
@@ -1,98 +1,109 @@
 package user
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"time"
 	"user-api/model"
+	"user-api/search"
 
-	"github.com/jinzhu/gorm"
 	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-var Db *gorm.DB
-
-// UserClientInterface defines the interface for user operations.
+// UserClientInterface defines the interface for user operations. Methods fail
+// with ErrNotFound when the user doesn't exist, a *DuplicateKeyError when an email
+// or username is taken and a *TransientError when the database is unavailable.
+//
+// UpdateUser and DeleteUser only write if the stored version of the user is still
+// the one given, and fail with ErrStaleVersion otherwise. UpdateUser and
+// RestoreUser increment the version.
+//
+// The writes add the audit entry they are given to the audit log, for the user
+// they changed, and either both are stored or neither is. An entry without an
+// action, as that of an update that changed nothing, isn't recorded.
 type UserClientInterface interface {
-	GetUserById(id int) model.User
-	GetUsers() model.Users
-	GetUserByEmail(email string) bool
-	InsertUser(user model.User) model.User
-	DeleteUser(id int) error
-	UpdateUser(user model.User) error
+	GetUserById(ctx context.Context, id int) (model.User, error)
+	GetUsers(ctx context.Context, query UserQuery) (UserPage, error)
+	EmailTaken(ctx context.Context, email string) (bool, error)
+	GetUserByUsername(ctx context.Context, username string) (model.User, error)
+	FindUserByEmail(ctx context.Context, email string) (model.User, error)
+	InsertUser(ctx context.Context, user model.User, entry model.AuditEntry) (model.User, error)
+	DeleteUser(ctx context.Context, id int, version int, entry model.AuditEntry) error
+	UpdateUser(ctx context.Context, user model.User, entry model.AuditEntry) error
+	RestoreUser(ctx context.Context, id int, entry model.AuditEntry) (model.User, error)
+	PurgeUsers(ctx context.Context, deletedBefore time.Time, entry model.AuditEntry) (int, error)
 }
 
-type UserClient struct{}
-
-func (UserClient) GetUserById(id int) model.User {
-	return GetUserById(id)
+type UserClient struct {
+	db *gorm.DB
 }
 
-func (UserClient) GetUsers() model.Users {
-	return GetUsers()
+func NewUserClient(db *gorm.DB) *UserClient {
+	return &UserClient{db: db}
 }
 
-func (UserClient) GetUserByEmail(email string) bool {
-	return GetUserByEmail(email)
-}
+func (c *UserClient) GetUserByUsername(ctx context.Context, username string) (model.User, error) {
+	var user model.User
+	result := c.db.WithContext(ctx).Where("user_name = ?", username).First(&user)
 
-func (UserClient) InsertUser(user model.User) model.User {
-	return InsertUser(user)
-}
+	log.Debug("User: ", user)
 
-func (UserClient) DeleteUser(id int) error {
-	return DeleteUser(id)
-}
+	if result.Error != nil {
+		return user, translateError("users", result.Error)
+	}
 
-func (UserClient) UpdateUser(user model.User) error {
-	return UpdateUser(user)
+	return user, nil
 }
 
-func GetUserByUsername(username string) (model.User, error) {
+func (c *UserClient) FindUserByEmail(ctx context.Context, email string) (model.User, error) {
 	var user model.User
-	result := Db.Where("user_name = ?", username).First(&user)
+	result := c.db.WithContext(ctx).Where("email = ?", email).First(&user)
 
 	log.Debug("User: ", user)
 
 	if result.Error != nil {
-		return user, result.Error
+		return user, translateError("users", result.Error)
 	}
 
 	return user, nil
 }
 
-func GetUserByEmail(email string) bool {
-	var user model.User
-	result := Db.Where("email = ?", email).First(&user)
-
-	log.Debug("User: ", user)
+// EmailTaken tells whether the email is taken. Deleted users keep their email
+// until they are purged, so they are included.
+func (c *UserClient) EmailTaken(ctx context.Context, email string) (bool, error) {
+	var count int64
+	result := c.db.WithContext(ctx).Unscoped().Model(&model.User{}).Where("email = ?", email).Count(&count)
 
 	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return false // No se encontró el usuario, el email no está registrado
-		}
-		// Manejo de otros errores, podría ser útil añadir un log aquí
 		log.Error("Error buscando usuario por email: ", result.Error)
-		return false // Asumimos que el email no está registrado si hay un error distinto
+		return false, translateError("users", result.Error)
 	}
 
-	return true // El usuario existe, el email está registrado
+	return count > 0, nil
 }
 
-func GetUserById(id int) model.User {
+func (c *UserClient) GetUserById(ctx context.Context, id int) (model.User, error) {
 	var user model.User
 
-	Db.Where("id = ?", id).First(&user)
+	result := c.db.WithContext(ctx).Where("id = ?", id).First(&user)
 	log.Debug("User: ", user)
 
-	return user
+	if result.Error != nil {
+		return user, translateError("users", result.Error)
+	}
+
+	return user, nil
 }
 
 //Checkear si existe un usuario en el sistema
 
-func CheckUserById(id int) bool {
+func (c *UserClient) CheckUserById(ctx context.Context, id int) bool {
 	var user model.User
 
 	// realza consulta a la base de datos: (con el id proporcionado como parametro)
-	result := Db.Where("id = ?", id).First(&user)
+	result := c.db.WithContext(ctx).Where("id = ?", id).First(&user)
 
 	if result.Error != nil {
 		return false
@@ -101,59 +112,190 @@ func CheckUserById(id int) bool {
 	return true
 }
 
-func GetUsers() model.Users {
-	var users model.Users
-	Db.Find(&users)
-
-	log.Debug("Users: ", users)
-
-	return users
-}
-
-func InsertUser(user model.User) model.User {
-	result := Db.Create(&user)
+func (c *UserClient) InsertUser(ctx context.Context, user model.User, entry model.AuditEntry) (model.User, error) {
+	user.Version = 1
+	user.SearchText = search.NewDocument(user).Text()
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, entry, user.Id)
+	})
 
-	if result.Error != nil {
-		//TODO Manage Errors
-		log.Error("")
+	if err != nil {
+		log.Error("Error inserting user: ", err)
+		return user, translateError("users", err)
 	}
 	log.Debug("User Created: ", user.Id)
-	return user
+	return user, nil
 }
 
-func DeleteUser(id int) error {
+func (c *UserClient) DeleteUser(ctx context.Context, id int, version int, entry model.AuditEntry) error {
 
 	var user model.User
 
 	// Find the user by ID first
-	result := Db.Where("id = ?", id).First(&user)
+	result := c.db.WithContext(ctx).Where("id = ?", id).First(&user)
 
 	// Check if the user exists
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			log.Warn("User not found for deletion, ID: ", id)
-			return result.Error // User not found
+			return ErrNotFound // User not found
 		}
 		log.Error("Error finding user for deletion: ", result.Error)
-		return result.Error // Other error occurred
+		return translateError("users", result.Error) // Other error occurred
 	}
 
-	// Proceed to delete the user
-	deleteResult := Db.Delete(&user)
+	// Proceed to delete the user. It's only marked as deleted until it's purged
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		deleteResult := tx.Where("version = ?", version).Delete(&user)
+		if deleteResult.Error != nil {
+			return deleteResult.Error
+		}
+		if deleteResult.RowsAffected == 0 {
+			return ErrStaleVersion
+		}
+		return recordAudit(tx, entry, id)
+	})
 
-	if deleteResult.Error != nil {
-		log.Error("Error deleting user: ", deleteResult.Error)
-		return deleteResult.Error // Deletion failed
+	if errors.Is(err, ErrStaleVersion) {
+		log.Warn("Stale version deleting user, ID: ", id)
+		return ErrStaleVersion
+	}
+	if err != nil {
+		log.Error("Error deleting user: ", err)
+		return translateError("users", err) // Deletion failed
 	}
 
 	log.Info("User deleted successfully, ID: ", id)
 	return nil // Deletion successful
 }
 
-func UpdateUser(user model.User) error {
-	if err := Db.Save(user).Error; err != nil {
-		fmt.Println(err)
-		return err // Return error if the update fails
+// UpdateUser saves every field of the user if its version is still the stored one.
+// It returns ErrNotFound if the user doesn't exist or is deleted.
+func (c *UserClient) UpdateUser(ctx context.Context, user model.User, entry model.AuditEntry) error {
+	version := user.Version
+	user.Version++
+	user.SearchText = search.NewDocument(user).Text()
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&user).Where("version = ?", version).Select("*").Omit("Id", "DeletedAt").Updates(&user)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStaleVersion
+		}
+		return recordAudit(tx, entry, user.Id)
+	})
+
+	if err != nil && !errors.Is(err, ErrStaleVersion) {
+		log.Error("Error updating user: ", err)
+		return translateError("users", err) // Return error if the update fails
+	}
+
+	if err != nil {
+		// Either the user is gone or someone else updated it first
+		if _, err := c.GetUserById(ctx, user.Id); err != nil {
+			return err
+		}
+		log.Warn("Stale version updating user, ID: ", user.Id)
+		return ErrStaleVersion
 	}
 	return nil
 }
+
+// RestoreUser clears the deletion mark of a deleted user. It returns ErrNotFound
+// if there is no deleted user with that id.
+func (c *UserClient) RestoreUser(ctx context.Context, id int, entry model.AuditEntry) (model.User, error) {
+	var user model.User
+
+	result := c.db.WithContext(ctx).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&user)
+	if result.Error != nil {
+		return user, translateError("users", result.Error)
+	}
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		restore := map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}
+		if err := tx.Unscoped().Model(&user).UpdateColumns(restore).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, entry, id)
+	})
+	if err != nil {
+		log.Error("Error restoring user: ", err)
+		return user, translateError("users", err)
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	user.Version++
+
+	log.Info("User restored successfully, ID: ", id)
+	return user, nil
+}
+
+// PurgeUsers permanently removes the users deleted before deletedBefore, together
+// with their tokens, and returns how many users were removed. A copy of entry is
+// added to the audit log for each of them in the same transaction.
+func (c *UserClient) PurgeUsers(ctx context.Context, deletedBefore time.Time, entry model.AuditEntry) (int, error) {
+	var ids []int
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locked so that a user restored meanwhile is either purged before or kept
+		err := tx.Unscoped().Model(&model.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at < ?", deletedBefore).Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		if err := tx.Where("user_id IN ?", ids).Delete(&model.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN ?", ids).Delete(&model.OneTimeToken{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("id IN ? AND deleted_at < ?", ids, deletedBefore).Delete(&model.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if int(result.RowsAffected) != len(ids) {
+			return errPurgeRace
+		}
+		return tx.Create(purgeEntries(entry, ids)).Error
+	})
+	if err != nil {
+		log.Error("Error purging users: ", err)
+		return 0, translateError("users", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	log.Info("Users purged: ", len(ids))
+	return len(ids), nil
+}
+
+// errPurgeRace rolls back a purge when one of its users was restored before it
+// could be removed. The next purge picks up the others.
+var errPurgeRace = errors.New("users restored while purging")
+
+// recordAudit adds entry to the audit log within tx, as a change to the user with
+// the given id. Entries without an action aren't recorded.
+func recordAudit(tx *gorm.DB, entry model.AuditEntry, targetId int) error {
+	if entry.Action == "" {
+		return nil
+	}
+	entry.Id = 0
+	entry.TargetId = targetId
+	return tx.Create(&entry).Error
+}
+
+// purgeEntries are the audit entries of purging the users with the given ids.
+func purgeEntries(entry model.AuditEntry, ids []int) []model.AuditEntry {
+	entries := make([]model.AuditEntry, len(ids))
+	for i, id := range ids {
+		entries[i] = entry
+		entries[i].Id = 0
+		entries[i].TargetId = id
+	}
+	return entries
+}
@@ -1,98 +1,140 @@
 package user
 
 import (
+	"context"
 	"testing"
 	"user-api/model"
 
-	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/sqlite"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
-func setupTestDB() *gorm.DB {
+// ctx is the context of the client calls in the tests
+var ctx = context.Background()
+
+func setupTestDB(t *testing.T) *gorm.DB {
 	// Create a new in-memory SQLite database for testing
-	db, err := gorm.Open("sqlite3", ":memory:")
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
 	if err != nil {
 		panic("failed to connect database")
 	}
-	db.AutoMigrate(&model.User{}) // Assuming model.User exists and has correct structure
-	Db = db                       // Assign the test DB to the package variable
+	// Every connection to ":memory:" gets its own empty database
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db.AutoMigrate(&model.User{}, &model.RefreshToken{}, &model.OneTimeToken{}, &model.AuditEntry{}) // Assuming model.User exists and has correct structure
 	return db
 }
 
+// insertUser stores a user through the client, failing the test if it can't.
+func insertUser(t *testing.T, client UserClientInterface, user model.User) model.User {
+	user, err := client.InsertUser(ctx, user, model.AuditEntry{})
+	if err != nil {
+		t.Fatal("inserting user: ", err)
+	}
+	return user
+}
+
 func TestGetUserByUsername(t *testing.T) {
-	db := setupTestDB()
-	defer db.Close()
+	db := setupTestDB(t)
+	client := NewUserClient(db)
 
 	// Seed the database
 	testUser := model.User{UserName: "testuser", Email: "testuser@example.com"}
 	db.Create(&testUser)
 
 	// Test case: user exists
-	retrievedUser, err := GetUserByUsername("testuser")
+	retrievedUser, err := client.GetUserByUsername(ctx, "testuser")
 	assert.NoError(t, err)
 	assert.Equal(t, testUser.UserName, retrievedUser.UserName)
 
 	// Test case: user does not exist
-	_, err = GetUserByUsername("nonexistentuser")
+	_, err = client.GetUserByUsername(ctx, "nonexistentuser")
 	assert.Error(t, err)
-	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	assert.Equal(t, ErrNotFound, err)
 }
 
 func TestGetUserByEmail(t *testing.T) {
-	db := setupTestDB()
-	defer db.Close()
+	db := setupTestDB(t)
+	client := NewUserClient(db)
 
 	// Seed the database
 	testUser := model.User{UserName: "testuser", Email: "testuser@example.com"}
 	db.Create(&testUser)
 
 	// Test case: email exists
-	assert.True(t, GetUserByEmail("testuser@example.com"))
+	taken, err := client.EmailTaken(ctx, "testuser@example.com")
+	assert.NoError(t, err)
+	assert.True(t, taken)
 
 	// Test case: email does not exist
-	assert.False(t, GetUserByEmail("nonexistent@example.com"))
+	taken, err = client.EmailTaken(ctx, "nonexistent@example.com")
+	assert.NoError(t, err)
+	assert.False(t, taken)
+}
+
+func TestFindUserByEmail(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+
+	// Seed the database
+	testUser := model.User{UserName: "testuser", Email: "testuser@example.com"}
+	db.Create(&testUser)
+
+	// Test case: email exists
+	retrievedUser, err := client.FindUserByEmail(ctx, "testuser@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, testUser.Id, retrievedUser.Id)
+
+	// Test case: email does not exist
+	_, err = client.FindUserByEmail(ctx, "nonexistent@example.com")
+	assert.Equal(t, ErrNotFound, err)
 }
 
 func TestGetUserById(t *testing.T) {
-	db := setupTestDB()
-	defer db.Close()
+	db := setupTestDB(t)
+	client := NewUserClient(db)
 
 	// Seed the database
 	testUser := model.User{UserName: "testuser", Email: "testuser@example.com"}
 	db.Create(&testUser)
 
 	// Test case: user exists
-	retrievedUser := GetUserById(int(testUser.Id))
+	retrievedUser, err := client.GetUserById(ctx, int(testUser.Id))
+	assert.NoError(t, err)
 	assert.Equal(t, testUser.Id, retrievedUser.Id)
 
 	// Test case: user does not exist
-	retrievedUser = GetUserById(999)          // Assuming 999 does not exist
-	assert.Equal(t, int(0), retrievedUser.Id) // Default Id should be zero if not found
+	_, err = client.GetUserById(ctx, 999) // Assuming 999 does not exist
+	assert.Equal(t, ErrNotFound, err)
 }
 
 func TestCheckUserById(t *testing.T) {
-	db := setupTestDB()
-	defer db.Close()
+	db := setupTestDB(t)
+	client := NewUserClient(db)
 
 	// Seed the database
 	testUser := model.User{UserName: "testuser", Email: "testuser@example.com"}
 	db.Create(&testUser)
 
 	// Test case: user exists
-	assert.True(t, CheckUserById(int(testUser.Id)))
+	assert.True(t, client.CheckUserById(ctx, int(testUser.Id)))
 
 	// Test case: user does not exist
-	assert.False(t, CheckUserById(999))
+	assert.False(t, client.CheckUserById(ctx, 999))
 }
 
 func TestInsertUser(t *testing.T) {
-	db := setupTestDB()
-	defer db.Close()
+	db := setupTestDB(t)
+	client := NewUserClient(db)
 
 	// Create a new user
 	testUser := model.User{UserName: "newuser", Email: "newuser@example.com"}
-	insertedUser := InsertUser(testUser)
+	insertedUser, err := client.InsertUser(ctx, testUser, model.AuditEntry{})
+	assert.NoError(t, err)
 
 	// Verify user was inserted correctly
 	var foundUser model.User
@@ -102,32 +144,32 @@ func TestInsertUser(t *testing.T) {
 }
 
 func TestDeleteUser(t *testing.T) {
-	db := setupTestDB()
-	defer db.Close()
+	db := setupTestDB(t)
+	client := NewUserClient(db)
 
 	// Seed the database
 	testUser := model.User{UserName: "testuser", Email: "testuser@example.com"}
 	db.Create(&testUser)
 
 	// Test case: delete existing user
-	err := DeleteUser(int(testUser.Id))
+	err := client.DeleteUser(ctx, int(testUser.Id), testUser.Version, model.AuditEntry{})
 	assert.NoError(t, err)
 
 	// Verify user was deleted
 	var foundUser model.User
 	err = db.Where("Id = ?", testUser.Id).First(&foundUser).Error
 	assert.Error(t, err)
-	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	assert.Equal(t, ErrNotFound, err)
 
 	// Test case: delete non-existing user
-	err = DeleteUser(999)
+	err = client.DeleteUser(ctx, 999, 1, model.AuditEntry{})
 	assert.Error(t, err)
-	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	assert.Equal(t, ErrNotFound, err)
 }
 
 func TestUpdateUser(t *testing.T) {
-	db := setupTestDB()
-	defer db.Close()
+	db := setupTestDB(t)
+	client := NewUserClient(db)
 
 	// Seed the database
 	testUser := model.User{UserName: "testuser", Email: "testuser@example.com"}
@@ -135,7 +177,7 @@ func TestUpdateUser(t *testing.T) {
 
 	// Update user information
 	testUser.Email = "updated@example.com"
-	err := UpdateUser(testUser)
+	err := client.UpdateUser(ctx, testUser, model.AuditEntry{})
 	assert.NoError(t, err)
 
 	// Verify user was updated
@@ -143,3 +185,40 @@ func TestUpdateUser(t *testing.T) {
 	db.Where("Id = ?", testUser.Id).First(&foundUser)
 	assert.Equal(t, "updated@example.com", foundUser.Email)
 }
+
+func TestUserClient_AuditFailureRollsBack(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+
+	user := insertUser(t, client, model.User{UserName: "ana", Email: "ana@example.com"})
+	deleted := insertUser(t, client, model.User{UserName: "bruno", Email: "bruno@example.com"})
+	assert.NoError(t, client.DeleteUser(ctx, deleted.Id, deleted.Version, model.AuditEntry{}))
+
+	// Without the audit log no change can be recorded
+	assert.NoError(t, db.Migrator().DropTable(&model.AuditEntry{}))
+
+	_, err := client.InsertUser(ctx, model.User{UserName: "carla", Email: "carla@example.com"}, model.AuditEntry{Action: model.AuditActionCreate})
+	assert.Error(t, err)
+	_, err = client.GetUserByUsername(ctx, "carla")
+	assert.Equal(t, ErrNotFound, err)
+
+	changed := user
+	changed.Name = "Ana"
+	assert.Error(t, client.UpdateUser(ctx, changed, model.AuditEntry{Action: model.AuditActionUpdate}))
+	stored, err := client.GetUserById(ctx, user.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, stored.Name)
+	assert.Equal(t, user.Version, stored.Version)
+
+	assert.Error(t, client.DeleteUser(ctx, user.Id, user.Version, model.AuditEntry{Action: model.AuditActionDelete}))
+	_, err = client.GetUserById(ctx, user.Id)
+	assert.NoError(t, err)
+
+	_, err = client.RestoreUser(ctx, deleted.Id, model.AuditEntry{Action: model.AuditActionRestore})
+	assert.Error(t, err)
+	_, err = client.GetUserById(ctx, deleted.Id)
+	assert.Equal(t, ErrNotFound, err)
+
+	// Writes without an entry don't touch the audit log
+	assert.NoError(t, client.UpdateUser(ctx, changed, model.AuditEntry{}))
+}
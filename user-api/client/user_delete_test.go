@@ -0,0 +1,149 @@
+package user
+
+import (
+	"testing"
+	"time"
+	"user-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestDeleteUser_IsSoft(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+	searchClient := NewUserSearchClient(db)
+
+	user := insertUser(t, client, model.User{Name: "Juan", UserName: "jperez", Email: "juan@example.com"})
+
+	assert.NoError(t, client.DeleteUser(ctx, user.Id, user.Version, model.AuditEntry{}))
+
+	// Hidden from every query
+	_, err := client.GetUserById(ctx, user.Id)
+	assert.Equal(t, ErrNotFound, err)
+	_, err = client.GetUserByUsername(ctx, "jperez")
+	assert.Equal(t, ErrNotFound, err)
+	page, err := client.GetUsers(ctx, UserQuery{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, page.Total)
+	hits, _ := searchClient.Search(ctx, "juan", 10)
+	assert.Empty(t, hits)
+
+	// But the row is still there and keeps its email
+	var stored model.User
+	assert.NoError(t, db.Unscoped().First(&stored, user.Id).Error)
+	assert.True(t, stored.DeletedAt.Valid)
+	taken, err := client.EmailTaken(ctx, "juan@example.com")
+	assert.NoError(t, err)
+	assert.True(t, taken)
+
+	// Deleting it again fails
+	assert.Equal(t, ErrNotFound, client.DeleteUser(ctx, user.Id, user.Version, model.AuditEntry{}))
+}
+
+func TestRestoreUser(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+
+	user := insertUser(t, client, model.User{Name: "Juan", UserName: "jperez", Email: "juan@example.com"})
+
+	// Test case: user not deleted
+	_, err := client.RestoreUser(ctx, user.Id, model.AuditEntry{})
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.NoError(t, client.DeleteUser(ctx, user.Id, user.Version, model.AuditEntry{}))
+
+	restored, err := client.RestoreUser(ctx, user.Id, model.AuditEntry{})
+	assert.NoError(t, err)
+	assert.Equal(t, "jperez", restored.UserName)
+	assert.False(t, restored.DeletedAt.Valid)
+	_, err = client.GetUserById(ctx, user.Id)
+	assert.NoError(t, err)
+
+	// Test case: user does not exist
+	_, err = client.RestoreUser(ctx, 999, model.AuditEntry{})
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestPurgeUsers(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+	refreshTokenClient := NewRefreshTokenClient(db)
+	oneTimeTokenClient := NewOneTimeTokenClient(db)
+
+	old := insertUser(t, client, model.User{UserName: "old", Email: "old@example.com"})
+	recent := insertUser(t, client, model.User{UserName: "recent", Email: "recent@example.com"})
+	active := insertUser(t, client, model.User{UserName: "active", Email: "active@example.com"})
+
+	refreshTokenClient.InsertRefreshToken(ctx, model.RefreshToken{UserId: old.Id, TokenHash: "old-hash", ExpiresAt: time.Now()})
+	oneTimeTokenClient.InsertOneTimeToken(ctx, model.OneTimeToken{UserId: old.Id, TokenHash: "old-hash", ExpiresAt: time.Now()})
+	refreshTokenClient.InsertRefreshToken(ctx, model.RefreshToken{UserId: active.Id, TokenHash: "active-hash", ExpiresAt: time.Now()})
+
+	client.DeleteUser(ctx, old.Id, old.Version, model.AuditEntry{})
+	client.DeleteUser(ctx, recent.Id, recent.Version, model.AuditEntry{})
+	db.Unscoped().Model(&old).UpdateColumn("deleted_at", time.Now().Add(-48*time.Hour))
+
+	purged, err := client.PurgeUsers(ctx, time.Now().Add(-24*time.Hour), model.AuditEntry{Action: model.AuditActionPurge, RequestId: "req-1", CreatedAt: time.Now()})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	var count int64
+	db.Unscoped().Model(&model.User{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+	taken, err := client.EmailTaken(ctx, "old@example.com")
+	assert.NoError(t, err)
+	assert.False(t, taken)
+	taken, err = client.EmailTaken(ctx, "recent@example.com")
+	assert.NoError(t, err)
+	assert.True(t, taken)
+
+	db.Model(&model.RefreshToken{}).Where("user_id = ?", old.Id).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&model.OneTimeToken{}).Where("user_id = ?", old.Id).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&model.RefreshToken{}).Where("user_id = ?", active.Id).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	var entries []model.AuditEntry
+	db.Find(&entries)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, model.AuditActionPurge, entries[0].Action)
+		assert.Equal(t, old.Id, entries[0].TargetId)
+		assert.Equal(t, "req-1", entries[0].RequestId)
+	}
+
+	// Test case: nothing to purge
+	purged, err = client.PurgeUsers(ctx, time.Now().Add(-24*time.Hour), model.AuditEntry{Action: model.AuditActionPurge, CreatedAt: time.Now()})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, purged)
+}
+
+func TestPurgeUsers_RestoredMeanwhile(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+
+	user := insertUser(t, client, model.User{UserName: "ana", Email: "ana@example.com"})
+	client.DeleteUser(ctx, user.Id, user.Version, model.AuditEntry{})
+
+	// Restore the user right after the purge picked it
+	restored := false
+	db.Callback().Query().After("gorm:query").Register("test:restore", func(tx *gorm.DB) {
+		if restored || tx.Statement.Table != "users" {
+			return
+		}
+		restored = true
+		tx.Session(&gorm.Session{NewDB: true}).Exec("UPDATE users SET deleted_at = NULL WHERE id = ?", user.Id)
+	})
+
+	_, err := client.PurgeUsers(ctx, time.Now().Add(time.Minute), model.AuditEntry{Action: model.AuditActionPurge, CreatedAt: time.Now()})
+	assert.Error(t, err)
+	assert.True(t, restored)
+
+	// The restore ran in the purge transaction and was rolled back with it, but
+	// the user wasn't removed
+	var count int64
+	db.Unscoped().Model(&model.User{}).Where("id = ?", user.Id).Count(&count)
+	assert.Equal(t, int64(1), count)
+	db.Model(&model.AuditEntry{}).Count(&count)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,177 @@
+package user
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"user-api/model"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// SortableColumns maps the public name of every sortable field to its column.
+var SortableColumns = map[string]string{
+	"id":        "id",
+	"name":      "name",
+	"last_name": "last_name",
+	"username":  "user_name",
+	"email":     "email",
+	"phone":     "phone",
+	"address":   "address",
+	"type":      "type",
+}
+
+const DefaultPageSize = 20
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// UserQuery selects a page of users. When Cursor is set it takes precedence over Offset.
+type UserQuery struct {
+	Limit       int
+	Offset      int
+	Cursor      string
+	SortBy      string // Key of SortableColumns, defaults to id
+	Descending  bool
+	NamePrefix  string
+	EmailDomain string
+	Type        *bool
+}
+
+// UserPage is a page of users plus what's needed to ask for the next one.
+type UserPage struct {
+	Users      model.Users
+	Total      int
+	NextCursor string // Empty on the last page
+}
+
+// cursor points right after the last row of a page for a given sort.
+type cursor struct {
+	SortBy     string      `json:"s"`
+	Descending bool        `json:"d"`
+	Value      interface{} `json:"v"`
+	Id         int         `json:"i"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(encoded string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// likeEscaper escapes LIKE wildcards with '!', which behaves the same on every dialect
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+func filterUsers(db *gorm.DB, query UserQuery) *gorm.DB {
+	if query.NamePrefix != "" {
+		db = db.Where("LOWER(name) LIKE ? ESCAPE '!'", likeEscaper.Replace(strings.ToLower(query.NamePrefix))+"%")
+	}
+	if query.EmailDomain != "" {
+		db = db.Where("LOWER(email) LIKE ? ESCAPE '!'", "%@"+likeEscaper.Replace(strings.ToLower(query.EmailDomain)))
+	}
+	if query.Type != nil {
+		db = db.Where(db.Statement.Quote("type")+" = ?", *query.Type)
+	}
+	return db
+}
+
+func sortValue(user model.User, sortBy string) interface{} {
+	switch sortBy {
+	case "name":
+		return user.Name
+	case "last_name":
+		return user.LastName
+	case "username":
+		return user.UserName
+	case "email":
+		return user.Email
+	case "phone":
+		return user.Phone
+	case "address":
+		return user.Address
+	case "type":
+		return user.Type
+	default:
+		return user.Id
+	}
+}
+
+func (c *UserClient) GetUsers(ctx context.Context, query UserQuery) (UserPage, error) {
+	var page UserPage
+
+	if query.Limit <= 0 {
+		query.Limit = DefaultPageSize
+	}
+	if query.SortBy == "" {
+		query.SortBy = "id"
+	}
+	column, ok := SortableColumns[query.SortBy]
+	if !ok {
+		return page, errors.New("unknown sort field " + query.SortBy)
+	}
+	quotedColumn := c.db.Statement.Quote(column)
+
+	var total int64
+	if err := filterUsers(c.db.WithContext(ctx).Model(&model.User{}), query).Count(&total).Error; err != nil {
+		log.Error("Error counting users: ", err)
+		return page, translateError("users", err)
+	}
+	page.Total = int(total)
+
+	direction, comparison := "ASC", ">"
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	db := filterUsers(c.db.WithContext(ctx), query)
+	if query.Cursor != "" {
+		after, err := decodeCursor(query.Cursor)
+		if err != nil || after.SortBy != query.SortBy || after.Descending != query.Descending {
+			return page, ErrInvalidCursor
+		}
+		// Keyset pagination: rows after (value, id) in the requested order
+		if column == "id" {
+			db = db.Where("id "+comparison+" ?", after.Id)
+		} else {
+			db = db.Where("("+quotedColumn+" "+comparison+" ? OR ("+quotedColumn+" = ? AND id "+comparison+" ?))", after.Value, after.Value, after.Id)
+		}
+	} else if query.Offset > 0 {
+		db = db.Offset(query.Offset)
+	}
+
+	if column != "id" {
+		db = db.Order(quotedColumn + " " + direction)
+	}
+	db = db.Order("id " + direction)
+
+	// One extra row tells whether there is a next page
+	var users model.Users
+	if err := db.Limit(query.Limit + 1).Find(&users).Error; err != nil {
+		log.Error("Error listing users: ", err)
+		return page, translateError("users", err)
+	}
+
+	if len(users) > query.Limit {
+		users = users[:query.Limit]
+		last := users[len(users)-1]
+		page.NextCursor = cursor{SortBy: query.SortBy, Descending: query.Descending, Value: sortValue(last, query.SortBy), Id: last.Id}.encode()
+	}
+	page.Users = users
+
+	log.Debug("Users: ", users)
+
+	return page, nil
+}
@@ -0,0 +1,126 @@
+package user
+
+import (
+	"fmt"
+	"testing"
+	"user-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func seedUsers(db *gorm.DB) {
+	users := model.Users{
+		{Name: "Mateo", LastName: "Negri", UserName: "mnegri", Email: "mateo@ucc.edu.ar"},
+		{Name: "Martina", LastName: "Lopez", UserName: "mlopez", Email: "martina@gmail.com", Type: true},
+		{Name: "Juan", LastName: "Perez", UserName: "jperez", Email: "juan@ucc.edu.ar"},
+		{Name: "Maria", LastName: "Gomez", UserName: "mgomez", Email: "maria@gmail.com"},
+		{Name: "Lucia", LastName: "Diaz", UserName: "ldiaz", Email: "lucia@UCC.edu.ar"},
+	}
+	for _, user := range users {
+		db.Create(&user)
+	}
+}
+
+func userNames(users model.Users) []string {
+	names := []string{}
+	for _, user := range users {
+		names = append(names, user.Name)
+	}
+	return names
+}
+
+func TestGetUsers_OffsetPagination(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+	seedUsers(db)
+
+	page, err := client.GetUsers(ctx, UserQuery{Limit: 2, Offset: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, page.Total)
+	assert.Equal(t, []string{"Juan", "Maria"}, userNames(page.Users))
+	assert.NotEmpty(t, page.NextCursor)
+
+	// Test case: last page
+	page, err = client.GetUsers(ctx, UserQuery{Limit: 2, Offset: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Lucia"}, userNames(page.Users))
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestGetUsers_CursorPagination(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+	seedUsers(db)
+
+	// Walk every page sorted by name descending
+	query := UserQuery{Limit: 2, SortBy: "name", Descending: true}
+	names := []string{}
+	for pages := 0; pages < 5; pages++ {
+		page, err := client.GetUsers(ctx, query)
+		assert.NoError(t, err)
+		names = append(names, userNames(page.Users)...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"Mateo", "Martina", "Maria", "Lucia", "Juan"}, names)
+}
+
+func TestGetUsers_InvalidCursor(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+	seedUsers(db)
+
+	_, err := client.GetUsers(ctx, UserQuery{Limit: 2, Cursor: "not base64!"})
+	assert.Equal(t, ErrInvalidCursor, err)
+
+	// Test case: cursor built for another sort
+	page, _ := client.GetUsers(ctx, UserQuery{Limit: 2, SortBy: "name"})
+	_, err = client.GetUsers(ctx, UserQuery{Limit: 2, SortBy: "email", Cursor: page.NextCursor})
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestGetUsers_Filters(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+	seedUsers(db)
+
+	page, err := client.GetUsers(ctx, UserQuery{Limit: 10, NamePrefix: "ma", SortBy: "name"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+	assert.Equal(t, []string{"Maria", "Martina", "Mateo"}, userNames(page.Users))
+
+	page, err = client.GetUsers(ctx, UserQuery{Limit: 10, EmailDomain: "ucc.edu.ar"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Mateo", "Juan", "Lucia"}, userNames(page.Users))
+
+	isAdmin := true
+	page, err = client.GetUsers(ctx, UserQuery{Limit: 10, Type: &isAdmin})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Martina"}, userNames(page.Users))
+
+	// Test case: wildcards in the prefix are matched literally
+	page, err = client.GetUsers(ctx, UserQuery{Limit: 10, NamePrefix: "%"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, page.Total)
+}
+
+func TestGetUsers_SortByEveryColumn(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+	seedUsers(db)
+
+	for sortBy := range SortableColumns {
+		page, err := client.GetUsers(ctx, UserQuery{Limit: 3, SortBy: sortBy})
+		assert.NoError(t, err, sortBy)
+
+		next, err := client.GetUsers(ctx, UserQuery{Limit: 3, SortBy: sortBy, Cursor: page.NextCursor})
+		assert.NoError(t, err, sortBy)
+		assert.Len(t, append(page.Users, next.Users...), 5, fmt.Sprint("sorting by ", sortBy))
+	}
+
+	_, err := client.GetUsers(ctx, UserQuery{Limit: 3, SortBy: "password"})
+	assert.Error(t, err)
+}
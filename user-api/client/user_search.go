@@ -0,0 +1,101 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"user-api/model"
+	"user-api/search"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SearchCandidates caps how many matching rows are ranked for a single query. The
+// rows that match best are kept, so common words don't push exact matches out.
+const SearchCandidates = 500
+
+// UserSearchClient is the search.Backend that queries the search_text column of the
+// users table. The column is written with every insert and update, so Index and
+// Remove have nothing to do.
+type UserSearchClient struct {
+	db         *gorm.DB
+	candidates int
+}
+
+func NewUserSearchClient(db *gorm.DB) *UserSearchClient {
+	return &UserSearchClient{db: db, candidates: SearchCandidates}
+}
+
+// Search returns the users whose search_text contains every term of the query,
+// ranked with search.Rank. The candidates are picked in SQL by how well the terms
+// match, whole words first, as search_text doesn't keep the fields apart for the
+// weights of search.Rank.
+func (c *UserSearchClient) Search(ctx context.Context, query string, limit int) ([]search.Hit, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []search.Hit{}, nil
+	}
+
+	db := c.db.WithContext(ctx)
+	for _, term := range terms {
+		db = db.Where("search_text LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(term)+"%")
+	}
+
+	var users model.Users
+	if err := db.Order(matchQuality(terms)).Limit(c.candidates).Find(&users).Error; err != nil {
+		log.Error("Error searching users: ", err)
+		return nil, translateError("users", err)
+	}
+
+	documents := make([]search.Document, 0, len(users))
+	for _, user := range users {
+		documents = append(documents, search.NewDocument(user))
+	}
+
+	return search.Rank(documents, terms, limit), nil
+}
+
+// searchWords is search_text with the characters that join the words of usernames
+// and emails turned into spaces, so LIKE can tell whole words and word prefixes.
+const searchWords = "REPLACE(REPLACE(REPLACE(REPLACE(search_text, '@', ' '), '.', ' '), '_', ' '), '-', ' ')"
+
+// matchQuality orders the users by how well they match the terms, best first and by
+// id on ties. As in search.Document.Score, each term counts 3 when it is a whole
+// word, 2 when it starts a word and 1 when it appears anywhere.
+func matchQuality(terms []string) clause.OrderBy {
+	scores := make([]string, 0, len(terms))
+	var vars []interface{}
+	for _, term := range terms {
+		escaped := likeEscaper.Replace(term)
+		scores = append(scores, "CASE"+
+			" WHEN "+searchWords+" = ? OR "+searchWords+" LIKE ? ESCAPE '!' OR "+searchWords+" LIKE ? ESCAPE '!' OR "+searchWords+" LIKE ? ESCAPE '!' THEN 3"+
+			" WHEN "+searchWords+" LIKE ? ESCAPE '!' OR "+searchWords+" LIKE ? ESCAPE '!' THEN 2"+
+			" ELSE 1 END")
+		vars = append(vars, term, escaped+" %", "% "+escaped, "% "+escaped+" %", escaped+"%", "% "+escaped+"%")
+	}
+
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:                "(" + strings.Join(scores, " + ") + ") DESC, id",
+		Vars:               vars,
+		WithoutParentheses: true,
+	}}
+}
+
+func (c *UserSearchClient) Index(ctx context.Context, user model.User) error {
+	return nil
+}
+
+func (c *UserSearchClient) Remove(ctx context.Context, id int) error {
+	return nil
+}
+
+// GetAllUsers loads every user, to fill an in-process search index.
+func (c *UserClient) GetAllUsers(ctx context.Context) (model.Users, error) {
+	var users model.Users
+	if err := c.db.WithContext(ctx).Find(&users).Error; err != nil {
+		log.Error("Error loading users: ", err)
+		return nil, translateError("users", err)
+	}
+	return users, nil
+}
@@ -0,0 +1,83 @@
+package user
+
+import (
+	"testing"
+	"user-api/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchUsers(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+	searchClient := NewUserSearchClient(db)
+
+	insertUser(t, client, model.User{Name: "José", LastName: "Pérez", UserName: "jperez", Email: "jose@example.com"})
+	insertUser(t, client, model.User{Name: "Josefina", LastName: "Gómez", UserName: "jgomez", Email: "josefina@example.com"})
+	insertUser(t, client, model.User{Name: "Ana", LastName: "Núñez", UserName: "a_nunez", Email: "ana@example.com"})
+
+	hits, err := searchClient.Search(ctx, "JOSE", 10)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 2)
+	assert.Equal(t, "jperez", hits[0].User.UserName)
+
+	hits, err = searchClient.Search(ctx, "núñez", 10)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+
+	// Test case: every term has to match
+	hits, err = searchClient.Search(ctx, "jose gomez", 10)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, "jgomez", hits[0].User.UserName)
+
+	// Test case: wildcards are matched literally
+	hits, err = searchClient.Search(ctx, "%", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+
+	hits, err = searchClient.Search(ctx, "a_n", 10)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+}
+
+func TestSearchUsers_BestCandidates(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+	searchClient := NewUserSearchClient(db)
+	searchClient.candidates = 3
+
+	// Older users that only contain the term, or start a word with it
+	insertUser(t, client, model.User{Name: "Ana", LastName: "Lopez", UserName: "anamaria", Email: "ana1@example.com"})
+	insertUser(t, client, model.User{Name: "Mariana", LastName: "Paz", UserName: "mpaz", Email: "mpaz@example.com"})
+	insertUser(t, client, model.User{Name: "Rosa", LastName: "Marianelli", UserName: "rmarianelli", Email: "rosa@example.com"})
+	insertUser(t, client, model.User{Name: "Luz", LastName: "Ruiz", UserName: "lruiz", Email: "maria.luz@example.com"})
+	// The exact match is the newest
+	insertUser(t, client, model.User{Name: "María", LastName: "Gómez", UserName: "maria", Email: "mgomez@example.com"})
+
+	hits, err := searchClient.Search(ctx, "maria", 10)
+
+	assert.NoError(t, err)
+	usernames := []string{}
+	for _, hit := range hits {
+		usernames = append(usernames, hit.User.UserName)
+	}
+	// Whole words first, then the oldest prefix, then search.Rank weighs the fields
+	assert.Equal(t, []string{"maria", "mpaz", "lruiz"}, usernames)
+}
+
+func TestSearchUsers_FollowsUpdates(t *testing.T) {
+	db := setupTestDB(t)
+	client := NewUserClient(db)
+	searchClient := NewUserSearchClient(db)
+
+	user := insertUser(t, client, model.User{Name: "Ramón", LastName: "Díaz", UserName: "rdz", Email: "ramon@example.com"})
+	user.LastName = "Sáenz"
+	assert.NoError(t, client.UpdateUser(ctx, user, model.AuditEntry{}))
+
+	hits, _ := searchClient.Search(ctx, "diaz", 10)
+	assert.Empty(t, hits)
+
+	hits, _ = searchClient.Search(ctx, "saenz", 10)
+	assert.Len(t, hits, 1)
+}
@@ -0,0 +1,345 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Storages the API can keep its data in.
+const (
+	StorageDB     = "db"
+	StorageMemory = "memory" // Lost on restart, for demos and tests
+)
+
+// Config holds every setting needed to start the API. Values are resolved in this
+// order, each one overriding the previous: defaults, config file, environment
+// variables and command-line flags.
+type Config struct {
+	Dev           bool               `yaml:"dev" toml:"dev"`         // Allows insecure shortcuts meant for local use only
+	Storage       string             `yaml:"storage" toml:"storage"` // db, or memory to run without a database
+	Server        ServerConfig       `yaml:"server" toml:"server"`
+	Database      DatabaseConfig     `yaml:"database" toml:"database"`
+	Log           LogConfig          `yaml:"log" toml:"log"`
+	Users         UsersConfig        `yaml:"users" toml:"users"`
+	Auth          AuthConfig         `yaml:"auth" toml:"auth"`
+	Notifications NotificationConfig `yaml:"notifications" toml:"notifications"`
+}
+
+type ServerConfig struct {
+	Port           int      `yaml:"port" toml:"port"`
+	RequestTimeout Duration `yaml:"request_timeout" toml:"request_timeout"` // Deadline of every request, database queries included
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level"`   // Any logrus level: debug, info, warn...
+	Format string `yaml:"format" toml:"format"` // text or json
+}
+
+type UsersConfig struct {
+	RequireVerifiedEmail bool     `yaml:"require_verified_email" toml:"require_verified_email"`
+	DeletedRetention     Duration `yaml:"deleted_retention" toml:"deleted_retention"` // How long a deleted user can be restored
+	PurgeInterval        Duration `yaml:"purge_interval" toml:"purge_interval"`
+	SearchBackend        string   `yaml:"search_backend" toml:"search_backend"` // db or memory
+}
+
+type AuthConfig struct {
+	JWTSecret       string   `yaml:"jwt_secret" toml:"jwt_secret"` // Signs the access tokens
+	AccessTokenTTL  Duration `yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"` // How long a refresh token can be exchanged for a new pair
+}
+
+// Duration is a time.Duration written like "90m" or "720h" in files, variables and flags.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Default returns the settings used when nothing overrides them. There is no default
+// database password.
+func Default() Config {
+	return Config{
+		Storage: StorageDB,
+		Server:  ServerConfig{Port: 8080, RequestTimeout: Duration(10 * time.Second)},
+		Database: DatabaseConfig{
+			Driver:  DriverMySQL,
+			SSLMode: "disable",
+			Host:    "localhost",
+			Port:    3307,
+			User:    "root",
+			Name:    "ing-sw-3",
+		},
+		Log: LogConfig{Level: "info", Format: "text"},
+		Users: UsersConfig{
+			DeletedRetention: Duration(30 * 24 * time.Hour),
+			PurgeInterval:    Duration(24 * time.Hour),
+			SearchBackend:    "db",
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:  Duration(15 * time.Minute),
+			RefreshTokenTTL: Duration(30 * 24 * time.Hour),
+		},
+		Notifications: DefaultNotifications(),
+	}
+}
+
+// setting binds a field of Config to its environment variable and flag.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	value interface{} // *string, *int, *bool or *Duration
+}
+
+func settings(c *Config) []setting {
+	return []setting{
+		{"DEV_MODE", "dev", "local development mode, never in production", &c.Dev},
+		{"STORAGE", "storage", "where data is kept (db, memory)", &c.Storage},
+		{"SERVER_PORT", "port", "HTTP port", &c.Server.Port},
+		{"REQUEST_TIMEOUT", "request-timeout", "deadline of every request, e.g. 10s", &c.Server.RequestTimeout},
+		{"DB_DRIVER", "db-driver", "database driver (mysql, postgres, sqlite)", &c.Database.Driver},
+		{"DB_HOST", "db-host", "database host", &c.Database.Host},
+		{"DB_PORT", "db-port", "database port", &c.Database.Port},
+		{"DB_USER", "db-user", "database user", &c.Database.User},
+		{"DB_PASSWORD", "db-password", "database password", &c.Database.Password},
+		{"DB_NAME", "db-name", "database name, or file path for sqlite", &c.Database.Name},
+		{"DB_SSL_MODE", "db-ssl-mode", "postgres sslmode", &c.Database.SSLMode},
+		{"LOG_LEVEL", "log-level", "log level (debug, info, warn, error)", &c.Log.Level},
+		{"LOG_FORMAT", "log-format", "log format (text, json)", &c.Log.Format},
+		{"REQUIRE_EMAIL_VERIFICATION", "require-email-verification", "block login until the email is verified", &c.Users.RequireVerifiedEmail},
+		{"DELETED_USER_RETENTION", "deleted-retention", "how long deleted users can be restored, e.g. 720h", &c.Users.DeletedRetention},
+		{"PURGE_INTERVAL", "purge-interval", "how often deleted users are purged, e.g. 24h", &c.Users.PurgeInterval},
+		{"SEARCH_BACKEND", "search-backend", "user search backend (db, memory)", &c.Users.SearchBackend},
+		{"JWT_SECRET", "jwt-secret", "secret that signs the access tokens", &c.Auth.JWTSecret},
+		{"ACCESS_TOKEN_TTL", "access-token-ttl", "how long an access token is valid, e.g. 15m", &c.Auth.AccessTokenTTL},
+		{"REFRESH_TOKEN_TTL", "refresh-token-ttl", "how long a refresh token is valid, e.g. 720h", &c.Auth.RefreshTokenTTL},
+		{"NOTIFICATION_EMAIL", "notification-email", "email notifier (log, file, smtp)", &c.Notifications.Email},
+		{"NOTIFICATION_SMS", "notification-sms", "SMS notifier (log, file, webhook)", &c.Notifications.SMS},
+		{"NOTIFICATION_FILE", "notification-file", "file of the file notifier", &c.Notifications.File},
+		{"SMTP_HOST", "smtp-host", "SMTP server host", &c.Notifications.SMTP.Host},
+		{"SMTP_PORT", "smtp-port", "SMTP server port", &c.Notifications.SMTP.Port},
+		{"SMTP_USERNAME", "smtp-username", "SMTP user, empty to send without authentication", &c.Notifications.SMTP.Username},
+		{"SMTP_PASSWORD", "smtp-password", "SMTP password", &c.Notifications.SMTP.Password},
+		{"SMTP_FROM", "smtp-from", "sender address of the emails", &c.Notifications.SMTP.From},
+		{"SMS_WEBHOOK_URL", "sms-webhook-url", "URL the SMS messages are posted to", &c.Notifications.SMSWebhook.URL},
+		{"SMS_WEBHOOK_TOKEN", "sms-webhook-token", "bearer token of the SMS webhook", &c.Notifications.SMSWebhook.Token},
+	}
+}
+
+func (s setting) set(raw string) error {
+	switch value := s.value.(type) {
+	case *string:
+		*value = raw
+	case *int:
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", s.flag, raw)
+		}
+		*value = parsed
+	case *bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", s.flag, raw)
+		}
+		*value = parsed
+	case *Duration:
+		if err := value.UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("%s must be a duration like 24h, got %q", s.flag, raw)
+		}
+	}
+	return nil
+}
+
+// Load resolves the configuration from the command-line arguments (without the
+// program name), the environment and the file named by --config or CONFIG_FILE.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	// Flags are parsed first to find the config file, but applied last
+	fs := flag.NewFlagSet("user-api", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path of a YAML or TOML config file")
+	flagValues := map[string]string{}
+	for _, s := range settings(&cfg) {
+		name := s.flag
+		collect := func(raw string) error {
+			flagValues[name] = raw
+			return nil
+		}
+		if _, ok := s.value.(*bool); ok {
+			fs.BoolFunc(name, s.usage, collect)
+		} else {
+			fs.Func(name, s.usage, collect)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(*configFile, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings(&cfg) {
+		if raw, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(raw); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+
+	for _, s := range settings(&cfg) {
+		if raw, ok := flagValues[s.flag]; ok {
+			if err := s.set(raw); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadFile overrides cfg with the values of a YAML (.yaml, .yml) or TOML (.toml)
+// file. Unknown keys are rejected so typos don't go unnoticed.
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(cfg)
+		if errors.Is(err, io.EOF) {
+			err = nil // Empty file
+		}
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(cfg)
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var problems []string
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		problems = append(problems, "server port must be between 1 and 65535")
+	}
+	if c.Server.RequestTimeout <= 0 {
+		problems = append(problems, "request timeout must be positive")
+	}
+	switch c.Storage {
+	case StorageDB:
+		problems = append(problems, c.Database.problems()...)
+	case StorageMemory:
+		// The database settings are ignored
+	default:
+		problems = append(problems, "storage must be db or memory")
+	}
+	if _, err := log.ParseLevel(c.Log.Level); err != nil {
+		problems = append(problems, "log level "+strconv.Quote(c.Log.Level)+" is not valid")
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		problems = append(problems, "log format must be text or json")
+	}
+	if c.Users.DeletedRetention <= 0 {
+		problems = append(problems, "deleted user retention must be positive")
+	}
+	if c.Users.PurgeInterval <= 0 {
+		problems = append(problems, "purge interval must be positive")
+	}
+	if c.Users.SearchBackend != "db" && c.Users.SearchBackend != "memory" {
+		problems = append(problems, "search backend must be db or memory")
+	}
+	if c.Auth.AccessTokenTTL <= 0 || c.Auth.RefreshTokenTTL <= 0 {
+		problems = append(problems, "token TTLs must be positive")
+	}
+	problems = append(problems, c.Notifications.problems()...)
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// MinJWTSecretLength is the minimum length in bytes of the JWT secret, the size of
+// the HS256 key.
+const MinJWTSecretLength = 32
+
+// ValidateServer reports the settings the server can't start without, on top of
+// Validate. Commands that don't serve requests, like migrate, don't need them.
+func (c Config) ValidateServer() error {
+	var problems []string
+
+	switch {
+	case c.Auth.JWTSecret == "" && !c.Dev:
+		problems = append(problems, "jwt secret is required (only dev mode runs without one)")
+	case c.Auth.JWTSecret != "" && len(c.Auth.JWTSecret) < MinJWTSecretLength:
+		problems = append(problems, fmt.Sprintf("jwt secret must be at least %d bytes", MinJWTSecretLength))
+	}
+	problems = append(problems, c.Notifications.serverProblems(c.Dev)...)
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration that is safe to log.
+func (c Config) Redacted() Config {
+	redact(&c.Database.Password)
+	redact(&c.Auth.JWTSecret)
+	redact(&c.Notifications.SMTP.Password)
+	redact(&c.Notifications.SMSWebhook.Token)
+	return c
+}
+
+func redact(secret *string) {
+	if *secret != "" {
+		*secret = "[REDACTED]"
+	}
+}
+
+// String dumps the redacted configuration as YAML.
+func (c Config) String() string {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Supported database drivers.
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+type DatabaseConfig struct {
+	Driver   string `yaml:"driver" toml:"driver"`
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`         // File path, or ":memory:", for sqlite
+	SSLMode  string `yaml:"ssl_mode" toml:"ssl_mode"` // Only used by postgres
+}
+
+// DSN is the connection string of the database for its driver.
+func (d DatabaseConfig) DSN() string {
+	switch d.Driver {
+	case DriverPostgres:
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(d.User, d.Password),
+			Host:     fmt.Sprintf("%s:%d", d.Host, d.Port),
+			Path:     d.Name,
+			RawQuery: url.Values{"sslmode": {d.SSLMode}}.Encode(),
+		}
+		return dsn.String()
+	case DriverSQLite:
+		return d.Name
+	default:
+		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8&parseTime=True", d.User, d.Password, d.Host, d.Port, d.Name)
+	}
+}
+
+// problems lists what's wrong with the settings of the chosen driver. SQLite only
+// needs the file.
+func (d DatabaseConfig) problems() []string {
+	var problems []string
+
+	switch d.Driver {
+	case DriverMySQL, DriverPostgres:
+		if d.Host == "" {
+			problems = append(problems, "database host is required")
+		}
+		if d.Port < 1 || d.Port > 65535 {
+			problems = append(problems, "database port must be between 1 and 65535")
+		}
+		if d.User == "" {
+			problems = append(problems, "database user is required")
+		}
+	case DriverSQLite:
+	default:
+		problems = append(problems, "database driver must be mysql, postgres or sqlite")
+	}
+
+	if d.Name == "" {
+		problems = append(problems, "database name is required")
+	}
+	return problems
+}
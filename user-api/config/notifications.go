@@ -0,0 +1,98 @@
+package config
+
+import "net/url"
+
+// Notifiers of each channel. There is no default: outside dev mode each channel must
+// name its notifier.
+const (
+	NotifierLog     = "log" // Writes the messages, tokens included, to the application log. Dev mode only
+	NotifierFile    = "file"
+	NotifierSMTP    = "smtp"    // Email only
+	NotifierWebhook = "webhook" // SMS only
+	NotifierNone    = "none"    // SMS only, drops the messages
+)
+
+type NotificationConfig struct {
+	Email      string           `yaml:"email" toml:"email"` // log, file or smtp
+	SMS        string           `yaml:"sms" toml:"sms"`     // log, file, webhook or none
+	File       string           `yaml:"file" toml:"file"`   // Used by the file notifier
+	SMTP       SMTPConfig       `yaml:"smtp" toml:"smtp"`
+	SMSWebhook SMSWebhookConfig `yaml:"sms_webhook" toml:"sms_webhook"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	Username string `yaml:"username" toml:"username"` // Empty to send without authentication
+	Password string `yaml:"password" toml:"password"`
+	From     string `yaml:"from" toml:"from"`
+}
+
+type SMSWebhookConfig struct {
+	URL   string `yaml:"url" toml:"url"`
+	Token string `yaml:"token" toml:"token"` // Sent as bearer token when not empty
+}
+
+func DefaultNotifications() NotificationConfig {
+	return NotificationConfig{
+		File: "notifications.log",
+		SMTP: SMTPConfig{Port: 587},
+	}
+}
+
+// problems lists what's wrong with the settings of the chosen notifiers.
+func (n NotificationConfig) problems() []string {
+	var problems []string
+
+	switch n.Email {
+	case "", NotifierLog, NotifierFile:
+	case NotifierSMTP:
+		if n.SMTP.Host == "" {
+			problems = append(problems, "smtp host is required")
+		}
+		if n.SMTP.Port < 1 || n.SMTP.Port > 65535 {
+			problems = append(problems, "smtp port must be between 1 and 65535")
+		}
+		if n.SMTP.From == "" {
+			problems = append(problems, "smtp from is required")
+		}
+	default:
+		problems = append(problems, "email notifier must be log, file or smtp")
+	}
+
+	switch n.SMS {
+	case "", NotifierLog, NotifierFile, NotifierNone:
+	case NotifierWebhook:
+		if webhook, err := url.Parse(n.SMSWebhook.URL); err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+			problems = append(problems, "sms webhook url must be an http or https URL")
+		}
+	default:
+		problems = append(problems, "sms notifier must be log, file, webhook or none")
+	}
+
+	if (n.Email == NotifierFile || n.SMS == NotifierFile) && n.File == "" {
+		problems = append(problems, "notification file is required")
+	}
+	return problems
+}
+
+// serverProblems lists the notifiers the server can't start with. Outside dev mode
+// they must be chosen, and not be the log notifier, which would leave the password
+// reset and verification tokens in the application log.
+func (n NotificationConfig) serverProblems(dev bool) []string {
+	if dev {
+		return nil
+	}
+
+	var problems []string
+	if n.Email == "" {
+		problems = append(problems, "email notifier is required (only dev mode runs without one)")
+	}
+	if n.SMS == "" {
+		problems = append(problems, "sms notifier is required, none to not send SMS (only dev mode runs without one)")
+	}
+	if n.Email == NotifierLog || n.SMS == NotifierLog {
+		problems = append(problems, "the log notifier is only allowed in dev mode")
+	}
+	return problems
+}
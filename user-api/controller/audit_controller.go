@@ -0,0 +1,37 @@
+package user
+
+import (
+	"net/http"
+	"user-api/dto"
+	"user-api/service"
+	e "user-api/utils/errors"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// AuditController serves the audit log.
+type AuditController struct {
+	auditService service.AuditServiceInterface
+}
+
+func NewAuditController(auditService service.AuditServiceInterface) *AuditController {
+	return &AuditController{auditService: auditService}
+}
+
+func (ac *AuditController) GetAuditEntries(c *gin.Context) {
+	var queryDto dto.AuditQueryDto
+	if err := c.ShouldBindQuery(&queryDto); err != nil {
+		log.Debug(err.Error())
+		c.Error(e.NewBadRequestApiError(e.MsgInvalidParams))
+		return
+	}
+
+	auditPageDto, err := ac.auditService.GetAuditEntries(c.Request.Context(), &queryDto)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, auditPageDto)
+}
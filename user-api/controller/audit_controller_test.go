@@ -0,0 +1,71 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-api/dto"
+	e "user-api/utils/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock the audit service
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) GetAuditEntries(ctx context.Context, queryDto *dto.AuditQueryDto) (*dto.AuditPageDto, e.ApiError) {
+	args := m.Called(ctx, queryDto)
+	if args.Get(1) == nil {
+		return args.Get(0).(*dto.AuditPageDto), nil
+	}
+	return args.Get(0).(*dto.AuditPageDto), args.Get(1).(e.ApiError)
+}
+
+func TestGetAuditEntries(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockAuditService)
+	controller := NewAuditController(mockService)
+
+	actorId := 9
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	pageDto := &dto.AuditPageDto{
+		Items: []dto.AuditEntryDto{{Id: 2, ActorId: &actorId, Action: "update", TargetId: 1, Changes: []dto.AuditChangeDto{
+			{Field: "name", Before: json.RawMessage(`"John"`), After: json.RawMessage(`"Johnny"`)},
+		}}},
+		Total: 1,
+		Limit: 10,
+	}
+	queryDto := &dto.AuditQueryDto{Limit: 10, ActorId: 9, Action: "update", From: from}
+	mockService.On("GetAuditEntries", mock.Anything, queryDto).Return(pageDto, nil)
+
+	router := setupRouter()
+	router.GET("/audit", controller.GetAuditEntries)
+
+	req, _ := http.NewRequest("GET", "/audit?limit=10&actor_id=9&action=update&from=2024-01-01T00:00:00Z", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response dto.AuditPageDto
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, response.Total)
+	assert.Equal(t, "name", response.Items[0].Changes[0].Field)
+	assert.JSONEq(t, `"Johnny"`, string(response.Items[0].Changes[0].After))
+
+	// Test case: malformed time
+	req, _ = http.NewRequest("GET", "/audit?from=yesterday", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	mockService.AssertNumberOfCalls(t, "GetAuditEntries", 1)
+}
@@ -0,0 +1,58 @@
+package user
+
+import (
+	"net/http"
+	"user-api/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (uc *UserController) ChangePassword(c *gin.Context) {
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
+
+	var passwordDto dto.PasswordChangeDto
+	if err := c.ShouldBindJSON(&passwordDto); err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	if apiErr := uc.userService.ChangePassword(c.Request.Context(), id, &passwordDto); apiErr != nil {
+		c.Error(apiErr)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (uc *UserController) ForgotPassword(c *gin.Context) {
+	var forgotDto dto.PasswordForgotDto
+	if err := c.ShouldBindJSON(&forgotDto); err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	if apiErr := uc.userService.ForgotPassword(c.Request.Context(), &forgotDto); apiErr != nil {
+		c.Error(apiErr)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+func (uc *UserController) ResetPassword(c *gin.Context) {
+	var resetDto dto.PasswordResetDto
+	if err := c.ShouldBindJSON(&resetDto); err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	if apiErr := uc.userService.ResetPassword(c.Request.Context(), &resetDto); apiErr != nil {
+		c.Error(apiErr)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
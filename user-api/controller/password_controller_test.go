@@ -0,0 +1,91 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-api/dto"
+	e "user-api/utils/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestChangePassword(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockUserService)
+	controller := NewUserController(mockService)
+
+	passwordDto := &dto.PasswordChangeDto{CurrentPassword: "old", NewPassword: "new"}
+	mockService.On("ChangePassword", mock.Anything, 1, passwordDto).Return(nil)
+
+	router := setupRouter()
+	router.PUT("/users/:id/password", controller.ChangePassword)
+
+	body, _ := json.Marshal(passwordDto)
+	req, _ := http.NewRequest("PUT", "/users/1/password", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	// Test case: wrong current password
+	wrongDto := &dto.PasswordChangeDto{CurrentPassword: "wrong", NewPassword: "new"}
+	mockService.On("ChangePassword", mock.Anything, 1, wrongDto).Return(e.NewBadRequestApiError(e.MsgWrongCurrentPassword))
+
+	body, _ = json.Marshal(wrongDto)
+	req, _ = http.NewRequest("PUT", "/users/1/password", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestForgotPassword(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockUserService)
+	controller := NewUserController(mockService)
+
+	forgotDto := &dto.PasswordForgotDto{Email: "jdoe@example.com"}
+	mockService.On("ForgotPassword", mock.Anything, forgotDto).Return(nil)
+
+	router := setupRouter()
+	router.POST("/password/forgot", controller.ForgotPassword)
+
+	body, _ := json.Marshal(forgotDto)
+	req, _ := http.NewRequest("POST", "/password/forgot", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	mockService.AssertCalled(t, "ForgotPassword", mock.Anything, forgotDto)
+}
+
+func TestResetPassword(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockUserService)
+	controller := NewUserController(mockService)
+
+	resetDto := &dto.PasswordResetDto{Token: "reset-token", NewPassword: "new"}
+	mockService.On("ResetPassword", mock.Anything, resetDto).Return(nil)
+
+	router := setupRouter()
+	router.POST("/password/reset", controller.ResetPassword)
+
+	body, _ := json.Marshal(resetDto)
+	req, _ := http.NewRequest("POST", "/password/reset", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	mockService.AssertCalled(t, "ResetPassword", mock.Anything, resetDto)
+}
@@ -0,0 +1,49 @@
+package user
+
+import (
+	"net/http"
+	"user-api/dto"
+	"user-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenController serves the refresh and logout endpoints.
+type TokenController struct {
+	tokenService service.TokenServiceInterface
+}
+
+func NewTokenController(tokenService service.TokenServiceInterface) *TokenController {
+	return &TokenController{tokenService: tokenService}
+}
+
+func (tc *TokenController) RefreshToken(c *gin.Context) {
+	var refreshTokenDto dto.RefreshTokenDto
+	if err := c.ShouldBindJSON(&refreshTokenDto); err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	tokenDto, err := tc.tokenService.RefreshTokens(c.Request.Context(), refreshTokenDto.RefreshToken)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenDto)
+}
+
+func (tc *TokenController) Logout(c *gin.Context) {
+	var refreshTokenDto dto.RefreshTokenDto
+	if err := c.ShouldBindJSON(&refreshTokenDto); err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	if err := tc.tokenService.Logout(c.Request.Context(), refreshTokenDto.RefreshToken); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,107 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-api/dto"
+	"user-api/model"
+	e "user-api/utils/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock the token service
+type MockTokenService struct {
+	mock.Mock
+}
+
+func (m *MockTokenService) IssueTokens(ctx context.Context, user model.User) (*dto.TokenDto, e.ApiError) {
+	args := m.Called(ctx, user)
+	tokenDto := args.Get(0).(*dto.TokenDto)
+	var apiErr e.ApiError
+	if args.Get(1) != nil {
+		apiErr = args.Get(1).(e.ApiError)
+	}
+	return tokenDto, apiErr
+}
+
+func (m *MockTokenService) RefreshTokens(ctx context.Context, refreshToken string) (*dto.TokenDto, e.ApiError) {
+	args := m.Called(ctx, refreshToken)
+	tokenDto := args.Get(0).(*dto.TokenDto)
+	var apiErr e.ApiError
+	if args.Get(1) != nil {
+		apiErr = args.Get(1).(e.ApiError)
+	}
+	return tokenDto, apiErr
+}
+
+func (m *MockTokenService) Logout(ctx context.Context, refreshToken string) e.ApiError {
+	args := m.Called(ctx, refreshToken)
+	var apiErr e.ApiError
+	if args.Get(0) != nil {
+		apiErr = args.Get(0).(e.ApiError)
+	}
+	return apiErr
+}
+
+func TestRefreshToken(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockTokenService)
+	controller := NewTokenController(mockService)
+
+	tokenDto := &dto.TokenDto{AccessToken: "new.jwt.token", RefreshToken: "new-refresh"}
+	mockService.On("RefreshTokens", mock.Anything, "old-refresh").Return(tokenDto, nil)
+	mockService.On("RefreshTokens", mock.Anything, "revoked-refresh").Return((*dto.TokenDto)(nil), e.NewUnauthorizedApiError(e.MsgInvalidRefreshToken))
+
+	router := setupRouter()
+	router.POST("/token/refresh", controller.RefreshToken)
+
+	body, _ := json.Marshal(dto.RefreshTokenDto{RefreshToken: "old-refresh"})
+	req, _ := http.NewRequest("POST", "/token/refresh", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response dto.TokenDto
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "new-refresh", response.RefreshToken)
+
+	// Test case: revoked token
+	body, _ = json.Marshal(dto.RefreshTokenDto{RefreshToken: "revoked-refresh"})
+	req, _ = http.NewRequest("POST", "/token/refresh", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestLogout(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockTokenService)
+	controller := NewTokenController(mockService)
+
+	mockService.On("Logout", mock.Anything, "current-refresh").Return(nil)
+
+	router := setupRouter()
+	router.POST("/logout", controller.Logout)
+
+	body, _ := json.Marshal(dto.RefreshTokenDto{RefreshToken: "current-refresh"})
+	req, _ := http.NewRequest("POST", "/logout", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	mockService.AssertCalled(t, "Logout", mock.Anything, "current-refresh")
+}
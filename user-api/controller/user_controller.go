@@ -1,9 +1,9 @@
 package user
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"user-api/dto"
 	"user-api/service"
 	e "user-api/utils/errors"
@@ -12,104 +12,254 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func DeleteUser(c *gin.Context) {
-	id, _ := strconv.Atoi(c.Param("id"))
+// UserController serves the user, login and password endpoints.
+type UserController struct {
+	userService service.UserServiceInterface
+}
 
-	err := service.UserService.DeleteUser(id)
+func NewUserController(userService service.UserServiceInterface) *UserController {
+	return &UserController{userService: userService}
+}
 
+// userId reads the id path param. If it isn't a number it reports the error and
+// returns false.
+func userId(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		apiErr, ok := err.(e.ApiError)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, err.Error())
-			return
-		}
-		c.JSON(apiErr.Status(), apiErr)
-		return
+		c.Error(e.NewBadRequestApiError(e.MsgInvalidUserId))
+		return 0, false
 	}
+	return id, true
+}
 
-	c.JSON(http.StatusOK, true)
+// ifMatch reads the version the changes of the request are based on from the
+// If-Match header, which carries the ETag of the user. If the header is missing or
+// isn't an ETag of ours it reports the error and returns false.
+//
+// "*" names no version, so it is answered as a missing header. Weak tags never
+// match under the strong comparison If-Match uses (RFC 9110, 13.1.1) and fail the
+// precondition.
+func ifMatch(c *gin.Context) (int, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		c.Error(e.NewPreconditionRequiredApiError(e.MsgIfMatchRequired))
+		return 0, false
+	}
+	if strings.HasPrefix(header, "W/") {
+		c.Error(e.NewPreconditionFailedApiError(e.MsgWeakETag))
+		return 0, false
+	}
 
+	version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(header, `"`), `"`))
+	if err != nil || !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) {
+		c.Error(e.NewBadRequestApiError(e.MsgInvalidIfMatch))
+		return 0, false
+	}
+	return version, true
 }
 
-func GetUserById(c *gin.Context) {
-	log.Debug("User id to load: " + c.Param("id"))
+// setETag sends the version of the user as the ETag of the response.
+func setETag(c *gin.Context, userDto *dto.UserDto) {
+	c.Header("ETag", `"`+strconv.Itoa(userDto.Version)+`"`)
+}
+
+// invalidBody reports a body that couldn't be parsed.
+func invalidBody(c *gin.Context, err error) {
+	log.Debug(err.Error())
+	c.Error(e.NewBadRequestApiError(e.MsgInvalidData))
+}
 
-	id, _ := strconv.Atoi(c.Param("id"))
-	var userDto *dto.UserDto
+func (uc *UserController) DeleteUser(c *gin.Context) {
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
 
-	userDto, err := service.UserService.GetUserById(id)
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
 
+	if err := uc.userService.DeleteUser(c.Request.Context(), id, version); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (uc *UserController) RestoreUser(c *gin.Context) {
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
+
+	userDto, err := uc.userService.RestoreUser(c.Request.Context(), id)
 	if err != nil {
-		/* if !ok {
-			c.JSON(http.StatusInternalServerError, err.Error())
-			return
-		} */
-		c.JSON(err.Status(), err)
+		c.Error(err)
 		return
 	}
+
+	setETag(c, userDto)
 	c.JSON(http.StatusOK, userDto)
 }
 
-func GetUsers(c *gin.Context) {
-	var usersDto dto.UsersDto
-	usersDto, err := service.UserService.GetUsers()
+func (uc *UserController) GetUserById(c *gin.Context) {
+	log.Debug("User id to load: " + c.Param("id"))
 
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
+
+	userDto, err := uc.userService.GetUserById(c.Request.Context(), id)
 	if err != nil {
-		apiErr, ok := err.(e.ApiError)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, err.Error())
-			return
-		}
-		c.JSON(apiErr.Status(), apiErr)
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, usersDto)
+	setETag(c, userDto)
+	c.JSON(http.StatusOK, userDto)
 }
 
-func UserInsert(c *gin.Context) {
-	var userDto dto.UserDto
-	err := c.BindJSON(&userDto)
+func (uc *UserController) GetUsers(c *gin.Context) {
+	var queryDto dto.UserListQueryDto
+	if err := c.ShouldBindQuery(&queryDto); err != nil {
+		log.Debug(err.Error())
+		c.Error(e.NewBadRequestApiError(e.MsgInvalidParams))
+		return
+	}
 
-	// Error Parsing json param
+	usersPageDto, err := uc.userService.GetUsers(c.Request.Context(), &queryDto)
 	if err != nil {
-		log.Error(err.Error())
-		c.JSON(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 
-	userDtoPtr, er := service.UserService.InsertUser(&userDto)
-	// Error del Insert
-	if er != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": er.Message()})
-		log.Debug(er.Message())
+	c.JSON(http.StatusOK, usersPageDto)
+}
+
+func (uc *UserController) SearchUsers(c *gin.Context) {
+	var searchDto dto.UserSearchQueryDto
+	if err := c.ShouldBindQuery(&searchDto); err != nil {
+		log.Debug(err.Error())
+		c.Error(e.NewBadRequestApiError(e.MsgInvalidParams))
+		return
+	}
+
+	resultDto, err := uc.userService.SearchUsers(c.Request.Context(), &searchDto)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, userDtoPtr)
+	c.JSON(http.StatusOK, resultDto)
 }
 
-func UpdateUser(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+func (uc *UserController) UserInsert(c *gin.Context) {
+	var userDto dto.UserCreateDto
+	if err := c.ShouldBindJSON(&userDto); err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	createdDto, err := uc.userService.InsertUser(c.Request.Context(), &userDto)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid user ID"})
+		c.Error(err)
 		return
 	}
 
-	var userDto dto.UserDto
-	if err := c.BindJSON(&userDto); err != nil {
-		log.Error(err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Datos invalidos"})
+	setETag(c, createdDto)
+	c.JSON(http.StatusCreated, createdDto)
+}
+
+func (uc *UserController) UpdateUser(c *gin.Context) {
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
 		return
 	}
 
-	// Call the service layer to update the user
-	updatedUser, updateErr := service.UserService.UpdateUser(id, &userDto)
-	if updateErr != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": updateErr.Error()})
-		fmt.Println(updateErr.Error())
+	var userDto dto.UserUpdateDto
+	if err := c.ShouldBindJSON(&userDto); err != nil {
+		invalidBody(c, err)
 		return
 	}
 
+	updatedUser, err := uc.userService.UpdateUser(c.Request.Context(), id, version, &userDto)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setETag(c, updatedUser)
 	c.JSON(http.StatusOK, updatedUser)
 }
+
+func (uc *UserController) PatchUser(c *gin.Context) {
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	// Plain JSON bodies are read as a merge patch
+	patchType := c.ContentType()
+	if patchType == gin.MIMEJSON {
+		patchType = service.MergePatch
+	}
+
+	patchedUser, apiErr := uc.userService.PatchUser(c.Request.Context(), id, version, patch, patchType)
+	if apiErr != nil {
+		c.Error(apiErr)
+		return
+	}
+
+	setETag(c, patchedUser)
+	c.JSON(http.StatusOK, patchedUser)
+}
+
+func (uc *UserController) Login(c *gin.Context) {
+	var loginDto dto.LoginDto
+	if err := c.ShouldBindJSON(&loginDto); err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	tokenDto, err := uc.userService.Login(c.Request.Context(), &loginDto)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenDto)
+}
+
+func (uc *UserController) VerifyEmail(c *gin.Context) {
+	verificationToken := c.Query("token")
+	if verificationToken == "" {
+		c.Error(e.NewBadRequestApiError(e.MsgVerificationTokenMissing))
+		return
+	}
+
+	if err := uc.userService.VerifyEmail(c.Request.Context(), verificationToken); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"email_verified": true})
+}
@@ -2,12 +2,14 @@ package user
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"user-api/dto"
-	"user-api/model"
+	"user-api/middleware"
 	"user-api/service"
 	"user-api/utils/errors"
 	e "user-api/utils/errors"
@@ -22,15 +24,15 @@ type MockUserService struct {
 	mock.Mock
 }
 
-func (m *MockUserService) DeleteUser(id int) error {
-	args := m.Called(id)
+func (m *MockUserService) DeleteUser(ctx context.Context, id int, version int) error {
+	args := m.Called(ctx, id, version)
 	return args.Error(0)
 }
 
-func (m *MockUserService) UpdateUser(id int, userDto *dto.UserDto) (model.User, error) {
-	args := m.Called(id, userDto)
+func (m *MockUserService) UpdateUser(ctx context.Context, id int, version int, userDto *dto.UserUpdateDto) (*dto.UserDto, e.ApiError) {
+	args := m.Called(ctx, id, version, userDto)
 
-	user := args.Get(0).(model.User)
+	user := args.Get(0).(*dto.UserDto)
 	var apiErr e.ApiError
 	if args.Get(1) != nil {
 		apiErr = args.Get(1).(e.ApiError)
@@ -39,8 +41,30 @@ func (m *MockUserService) UpdateUser(id int, userDto *dto.UserDto) (model.User,
 	return user, apiErr
 }
 
-func (m *MockUserService) GetUserById(id int) (*dto.UserDto, e.ApiError) {
-	args := m.Called(id)
+func (m *MockUserService) PatchUser(ctx context.Context, id int, version int, patch []byte, patchType string) (*dto.UserDto, e.ApiError) {
+	args := m.Called(ctx, id, version, patch, patchType)
+
+	user := args.Get(0).(*dto.UserDto)
+	var apiErr e.ApiError
+	if args.Get(1) != nil {
+		apiErr = args.Get(1).(e.ApiError)
+	}
+
+	return user, apiErr
+}
+
+func (m *MockUserService) SearchUsers(ctx context.Context, searchDto *dto.UserSearchQueryDto) (*dto.UserSearchResultDto, e.ApiError) {
+	args := m.Called(ctx, searchDto)
+	resultDto := args.Get(0).(*dto.UserSearchResultDto)
+	var apiErr e.ApiError
+	if args.Get(1) != nil {
+		apiErr = args.Get(1).(e.ApiError)
+	}
+	return resultDto, apiErr
+}
+
+func (m *MockUserService) RestoreUser(ctx context.Context, id int) (*dto.UserDto, e.ApiError) {
+	args := m.Called(ctx, id)
 	userDto := args.Get(0).(*dto.UserDto)
 	var apiErr e.ApiError
 	if args.Get(1) != nil {
@@ -49,18 +73,37 @@ func (m *MockUserService) GetUserById(id int) (*dto.UserDto, e.ApiError) {
 	return userDto, apiErr
 }
 
-func (m *MockUserService) GetUsers() (dto.UsersDto, e.ApiError) {
-	args := m.Called()
-	usersDto := args.Get(0).(dto.UsersDto)
+func (m *MockUserService) PurgeDeletedUsers(ctx context.Context) (int, e.ApiError) {
+	args := m.Called(ctx)
 	var apiErr e.ApiError
 	if args.Get(1) != nil {
 		apiErr = args.Get(1).(e.ApiError)
 	}
-	return usersDto, apiErr
+	return args.Int(0), apiErr
 }
 
-func (m *MockUserService) InsertUser(userDto *dto.UserDto) (*dto.UserDto, errors.ApiError) {
-	args := m.Called(userDto)
+func (m *MockUserService) GetUserById(ctx context.Context, id int) (*dto.UserDto, e.ApiError) {
+	args := m.Called(ctx, id)
+	userDto := args.Get(0).(*dto.UserDto)
+	var apiErr e.ApiError
+	if args.Get(1) != nil {
+		apiErr = args.Get(1).(e.ApiError)
+	}
+	return userDto, apiErr
+}
+
+func (m *MockUserService) GetUsers(ctx context.Context, queryDto *dto.UserListQueryDto) (*dto.UsersPageDto, e.ApiError) {
+	args := m.Called(ctx, queryDto)
+	usersPageDto := args.Get(0).(*dto.UsersPageDto)
+	var apiErr e.ApiError
+	if args.Get(1) != nil {
+		apiErr = args.Get(1).(e.ApiError)
+	}
+	return usersPageDto, apiErr
+}
+
+func (m *MockUserService) InsertUser(ctx context.Context, userDto *dto.UserCreateDto) (*dto.UserDto, errors.ApiError) {
+	args := m.Called(ctx, userDto)
 	newUserDto := args.Get(0).(*dto.UserDto)
 	var apiErr errors.ApiError
 	if args.Get(1) != nil {
@@ -69,59 +112,169 @@ func (m *MockUserService) InsertUser(userDto *dto.UserDto) (*dto.UserDto, errors
 	return newUserDto, apiErr
 }
 
-func setupRouter() *gin.Engine {
+func (m *MockUserService) Login(ctx context.Context, loginDto *dto.LoginDto) (*dto.TokenDto, e.ApiError) {
+	args := m.Called(ctx, loginDto)
+	tokenDto := args.Get(0).(*dto.TokenDto)
+	var apiErr e.ApiError
+	if args.Get(1) != nil {
+		apiErr = args.Get(1).(e.ApiError)
+	}
+	return tokenDto, apiErr
+}
+
+func (m *MockUserService) ChangePassword(ctx context.Context, id int, passwordDto *dto.PasswordChangeDto) e.ApiError {
+	args := m.Called(ctx, id, passwordDto)
+	var apiErr e.ApiError
+	if args.Get(0) != nil {
+		apiErr = args.Get(0).(e.ApiError)
+	}
+	return apiErr
+}
+
+func (m *MockUserService) ForgotPassword(ctx context.Context, forgotDto *dto.PasswordForgotDto) e.ApiError {
+	args := m.Called(ctx, forgotDto)
+	var apiErr e.ApiError
+	if args.Get(0) != nil {
+		apiErr = args.Get(0).(e.ApiError)
+	}
+	return apiErr
+}
+
+func (m *MockUserService) ResetPassword(ctx context.Context, resetDto *dto.PasswordResetDto) e.ApiError {
+	args := m.Called(ctx, resetDto)
+	var apiErr e.ApiError
+	if args.Get(0) != nil {
+		apiErr = args.Get(0).(e.ApiError)
+	}
+	return apiErr
+}
+
+func (m *MockUserService) VerifyEmail(ctx context.Context, verificationToken string) e.ApiError {
+	args := m.Called(ctx, verificationToken)
+	var apiErr e.ApiError
+	if args.Get(0) != nil {
+		apiErr = args.Get(0).(e.ApiError)
+	}
+	return apiErr
+}
+
+func init() {
 	gin.SetMode(gin.TestMode)
+}
+
+func setupRouter() *gin.Engine {
 	router := gin.Default()
+	router.Use(middleware.RequestID(), middleware.Errors())
 	return router
 }
 
+// assertNoSecrets fails if a response body exposes a password or a bcrypt hash
+func assertNoSecrets(t *testing.T, body string) {
+	assert.NotContains(t, body, "password")
+	assert.NotContains(t, body, "$2a$")
+}
+
 func TestDeleteUser(t *testing.T) {
+	t.Parallel()
+
 	mockService := new(MockUserService)
-	service.UserService = mockService // Replace service with mock
+	controller := NewUserController(mockService)
 
 	// Test case: Successful deletion
-	mockService.On("DeleteUser", 1).Return(nil)
+	mockService.On("DeleteUser", mock.Anything, 1, 3).Return(nil)
 	router := setupRouter()
-	router.DELETE("/users/:id", DeleteUser)
+	router.DELETE("/users/:id", controller.DeleteUser)
 
 	req, _ := http.NewRequest("DELETE", "/users/1", nil)
+	req.Header.Set("If-Match", `"3"`)
 	resp := httptest.NewRecorder()
 	router.ServeHTTP(resp, req)
 
-	assert.Equal(t, http.StatusOK, resp.Code)
-	mockService.AssertCalled(t, "DeleteUser", 1)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Empty(t, resp.Body.String())
+	mockService.AssertCalled(t, "DeleteUser", mock.Anything, 1, 3)
 
 	// Test case: User not found
-	mockService.On("DeleteUser", 999).Return(e.NewBadRequestApiError("User not found"))
+	mockService.On("DeleteUser", mock.Anything, 999, 1).Return(e.NewNotFoundApiError(e.MsgUserNotFound))
 	req, _ = http.NewRequest("DELETE", "/users/999", nil)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("X-Request-ID", "req-999")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"message":"Usuario no encontrado","error":"not_found","status":404,"cause":[],"request_id":"req-999"}`, resp.Body.String())
+	mockService.AssertCalled(t, "DeleteUser", mock.Anything, 999, 1)
+
+	// Test case: invalid id
+	req, _ = http.NewRequest("DELETE", "/users/abc", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	mockService.AssertNotCalled(t, "DeleteUser", mock.Anything, 0, mock.Anything)
+
+	// Test case: no If-Match
+	req, _ = http.NewRequest("DELETE", "/users/1", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusPreconditionRequired, resp.Code)
+	mockService.AssertNumberOfCalls(t, "DeleteUser", 2)
+}
+
+func TestRestoreUser(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockUserService)
+	controller := NewUserController(mockService)
+
+	mockService.On("RestoreUser", mock.Anything, 1).Return(&dto.UserDto{Id: 1, UserName: "jdoe"}, nil)
+	mockService.On("RestoreUser", mock.Anything, 2).Return((*dto.UserDto)(nil), e.NewBadRequestApiError(e.MsgDeletedUserNotFound))
+
+	router := setupRouter()
+	router.POST("/users/:id/restore", controller.RestoreUser)
+
+	req, _ := http.NewRequest("POST", "/users/1/restore", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"username":"jdoe"`)
+
+	// Test case: user not deleted
+	req, _ = http.NewRequest("POST", "/users/2/restore", nil)
 	resp = httptest.NewRecorder()
 	router.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
-	mockService.AssertCalled(t, "DeleteUser", 999)
 }
 
 func TestGetUserById(t *testing.T) {
+	t.Parallel()
 
 	expectedUser := &dto.UserDto{
 		Id:       1,
 		Name:     "test",
 		LastName: "test",
 		UserName: "testuser",
+		Version:  7,
 	}
 
 	mockService := new(MockUserService)
-	service.UserService = mockService
-	mockService.On("GetUserById", 1).Return(expectedUser, nil)
+	controller := NewUserController(mockService)
+	mockService.On("GetUserById", mock.Anything, 1).Return(expectedUser, nil)
 
 	router := setupRouter()
-	router.GET("/user/:id", GetUserById)
+	router.GET("/user/:id", controller.GetUserById)
 
 	req, _ := http.NewRequest("GET", "/user/1", nil)
 	resp := httptest.NewRecorder()
 	router.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"7"`, resp.Header().Get("ETag"))
+	assertNoSecrets(t, resp.Body.String())
 
 	var response dto.UserDto
 	err := json.Unmarshal(resp.Body.Bytes(), &response)
@@ -129,11 +282,11 @@ func TestGetUserById(t *testing.T) {
 	assert.Equal(t, expectedUser.Id, response.Id)
 	assert.Equal(t, expectedUser.UserName, response.UserName)
 
-	mockService.AssertCalled(t, "GetUserById", 1)
+	mockService.AssertCalled(t, "GetUserById", mock.Anything, 1)
 
 	/*
 		 	// Test case: User not found
-			mockService.On("GetUserById", 999).Return(nil, e.NewBadRequestApiError("User not found"))
+			mockService.On("GetUserById", mock.Anything, 999).Return(nil, e.NewBadRequestApiError("User not found"))
 			req, _ = http.NewRequest("GET", "/user/999", nil)
 			resp = httptest.NewRecorder()
 			router.ServeHTTP(resp, req)
@@ -142,15 +295,41 @@ func TestGetUserById(t *testing.T) {
 	*/
 }
 
+func TestGetUserById_PassesRequestDeadline(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockUserService)
+	controller := NewUserController(mockService)
+	hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	})
+	mockService.On("GetUserById", hasDeadline, 1).Return(&dto.UserDto{Id: 1}, nil)
+
+	router := setupRouter()
+	router.Use(middleware.Timeout(time.Second))
+	router.GET("/user/:id", controller.GetUserById)
+
+	req, _ := http.NewRequest("GET", "/user/1", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	mockService.AssertExpectations(t)
+}
+
 func TestUserInsert(t *testing.T) {
+	t.Parallel()
+
 	mockService := new(MockUserService)
-	service.UserService = mockService
+	controller := NewUserController(mockService)
 
-	userDto := &dto.UserDto{UserName: "newuser"}
-	mockService.On("InsertUser", userDto).Return(userDto, nil)
+	userDto := &dto.UserCreateDto{UserName: "newuser", Password: "password123"}
+	createdDto := &dto.UserDto{Id: 1, UserName: "newuser"}
+	mockService.On("InsertUser", mock.Anything, userDto).Return(createdDto, nil)
 
 	router := setupRouter()
-	router.POST("/users", UserInsert)
+	router.POST("/users", controller.UserInsert)
 
 	userJSON, _ := json.Marshal(userDto)
 	req, _ := http.NewRequest("POST", "/users", bytes.NewBuffer(userJSON))
@@ -160,6 +339,7 @@ func TestUserInsert(t *testing.T) {
 	router.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusCreated, resp.Code)
+	assertNoSecrets(t, resp.Body.String())
 
 	var response dto.UserDto
 	err := json.Unmarshal(resp.Body.Bytes(), &response)
@@ -167,30 +347,78 @@ func TestUserInsert(t *testing.T) {
 	assert.Equal(t, userDto.UserName, response.UserName)
 }
 
+func TestUserInsert_Conflict(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockUserService)
+	controller := NewUserController(mockService)
+	mockService.On("InsertUser", mock.Anything, mock.Anything).Return((*dto.UserDto)(nil), e.NewDuplicateApiError(e.MsgEmailTaken, e.CauseList{}))
+
+	router := setupRouter()
+	router.POST("/users", controller.UserInsert)
+
+	req, _ := http.NewRequest("POST", "/users", bytes.NewBufferString(`{"username":"newuser","email":"taken@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.Contains(t, resp.Body.String(), "El email ya está registrado")
+}
+
+func TestUserInsert_IgnoresType(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockUserService)
+	controller := NewUserController(mockService)
+
+	// The type field is not part of the signup body, so it never reaches the service
+	mockService.On("InsertUser", mock.Anything, &dto.UserCreateDto{UserName: "newuser"}).Return(&dto.UserDto{Id: 1, UserName: "newuser"}, nil)
+
+	router := setupRouter()
+	router.POST("/users", controller.UserInsert)
+
+	req, _ := http.NewRequest("POST", "/users", bytes.NewBufferString(`{"username":"newuser","type":true}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	mockService.AssertExpectations(t)
+}
+
 func TestUpdateUser(t *testing.T) {
+	t.Parallel()
+
 	mockService := new(MockUserService)
-	service.UserService = mockService
+	controller := NewUserController(mockService)
 
-	userModel := model.User{UserName: "updateduser"}
-	userDto := &dto.UserDto{UserName: "updateduser"}
-	mockService.On("UpdateUser", 1, userDto).Return(userModel, nil)
+	updatedDto := &dto.UserDto{Id: 1, UserName: "updateduser", Version: 5}
+	userDto := &dto.UserUpdateDto{UserName: "updateduser"}
+	mockService.On("UpdateUser", mock.Anything, 1, 4, userDto).Return(updatedDto, nil)
 
 	router := setupRouter()
-	router.PUT("/users/:id", UpdateUser)
+	router.PUT("/users/:id", controller.UpdateUser)
 
 	userJSON, _ := json.Marshal(userDto)
 	req, _ := http.NewRequest("PUT", "/users/1", bytes.NewBuffer(userJSON))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"4"`)
 
 	resp := httptest.NewRecorder()
 	router.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"5"`, resp.Header().Get("ETag"))
+	assertNoSecrets(t, resp.Body.String())
+	assert.NotContains(t, resp.Body.String(), "version")
 
 	var response dto.UserDto
 	err := json.Unmarshal(resp.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, userModel.UserName, response.UserName)
+	assert.Equal(t, updatedDto.UserName, response.UserName)
 
 	// Test case: invalid user ID format
 	req, _ = http.NewRequest("PUT", "/users/invalid", bytes.NewBuffer(userJSON))
@@ -199,25 +427,225 @@ func TestUpdateUser(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
 }
 
+func TestUpdateUser_IfMatch(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockUserService)
+	controller := NewUserController(mockService)
+
+	userDto := &dto.UserUpdateDto{UserName: "updateduser"}
+	mockService.On("UpdateUser", mock.Anything, 1, 1, userDto).Return((*dto.UserDto)(nil), e.NewConflictApiError("user 1"))
+
+	router := setupRouter()
+	router.PUT("/users/:id", controller.UpdateUser)
+	userJSON, _ := json.Marshal(userDto)
+
+	for _, testCase := range []struct {
+		ifMatch string
+		status  int
+	}{
+		{"", http.StatusPreconditionRequired},
+		{"1", http.StatusBadRequest},
+		{`W/"1"`, http.StatusPreconditionFailed},
+		{"*", http.StatusPreconditionRequired},
+		{`"1"`, http.StatusConflict}, // The user changed since version 1
+	} {
+		req, _ := http.NewRequest("PUT", "/users/1", bytes.NewBuffer(userJSON))
+		req.Header.Set("Content-Type", "application/json")
+		if testCase.ifMatch != "" {
+			req.Header.Set("If-Match", testCase.ifMatch)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, testCase.status, resp.Code, testCase.ifMatch)
+		assert.Empty(t, resp.Header().Get("ETag"), testCase.ifMatch)
+	}
+
+	mockService.AssertNumberOfCalls(t, "UpdateUser", 1)
+}
+
 func TestGetUsers(t *testing.T) {
+	t.Parallel()
+
 	mockService := new(MockUserService)
-	service.UserService = mockService
+	controller := NewUserController(mockService)
 
 	usersDto := dto.UsersDto{{Id: 1, UserName: "testuser1"}, {Id: 2, UserName: "testuser2"}}
-	mockService.On("GetUsers").Return(usersDto, nil)
+	usersPageDto := &dto.UsersPageDto{Items: usersDto, Total: 5, Limit: 2, NextCursor: "next"}
+	isAdmin := true
+	queryDto := &dto.UserListQueryDto{Limit: 2, Sort: "-name", Name: "te", EmailDomain: "example.com", Type: &isAdmin}
+	mockService.On("GetUsers", mock.Anything, queryDto).Return(usersPageDto, nil)
+
+	router := setupRouter()
+	router.GET("/users", controller.GetUsers)
+
+	req, _ := http.NewRequest("GET", "/users?limit=2&sort=-name&name=te&email_domain=example.com&type=true", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assertNoSecrets(t, resp.Body.String())
+
+	var response dto.UsersPageDto
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Items, 2)
+	assert.Equal(t, 5, response.Total)
+	assert.Equal(t, "next", response.NextCursor)
+	assert.Equal(t, usersDto[0].UserName, response.Items[0].UserName)
+
+	// Test case: malformed query parameter
+	req, _ = http.NewRequest("GET", "/users?limit=many", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestSearchUsers(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockUserService)
+	controller := NewUserController(mockService)
+
+	resultDto := &dto.UserSearchResultDto{Items: []dto.UserSearchHitDto{
+		{UserDto: dto.UserDto{Id: 1, Name: "José", UserName: "jperez"}, Score: 9},
+	}}
+	mockService.On("SearchUsers", mock.Anything, &dto.UserSearchQueryDto{Q: "jose perez", Limit: 5}).Return(resultDto, nil)
+
+	router := setupRouter()
+	router.GET("/users/search", controller.SearchUsers)
+
+	req, _ := http.NewRequest("GET", "/users/search?q=jose+perez&limit=5", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assertNoSecrets(t, resp.Body.String())
+	assert.JSONEq(t, `{"items":[{"id":1,"name":"José","last_name":"","username":"jperez","phone":0,"address":"","email":"","type":false,"email_verified":false,"score":9}]}`, resp.Body.String())
+
+	// Test case: malformed query parameter
+	req, _ = http.NewRequest("GET", "/users/search?q=jose&limit=all", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockUserService)
+	controller := NewUserController(mockService)
+
+	loginDto := &dto.LoginDto{Login: "jdoe", Password: "password123"}
+	tokenDto := &dto.TokenDto{AccessToken: "signed.jwt.token", TokenType: "Bearer"}
+	mockService.On("Login", mock.Anything, loginDto).Return(tokenDto, nil)
 
 	router := setupRouter()
-	router.GET("/users", GetUsers)
+	router.POST("/login", controller.Login)
+
+	loginJSON, _ := json.Marshal(loginDto)
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(loginJSON))
+	req.Header.Set("Content-Type", "application/json")
 
-	req, _ := http.NewRequest("GET", "/users", nil)
 	resp := httptest.NewRecorder()
 	router.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusOK, resp.Code)
 
-	var response dto.UsersDto
+	var response dto.TokenDto
 	err := json.Unmarshal(resp.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Len(t, response, 2)
-	assert.Equal(t, usersDto[0].UserName, response[0].UserName)
+	assert.Equal(t, tokenDto.AccessToken, response.AccessToken)
+
+	// Test case: invalid credentials
+	badLoginDto := &dto.LoginDto{Login: "jdoe", Password: "wrong"}
+	mockService.On("Login", mock.Anything, badLoginDto).Return((*dto.TokenDto)(nil), e.NewUnauthorizedApiError(e.MsgWrongCredentials))
+
+	loginJSON, _ = json.Marshal(badLoginDto)
+	req, _ = http.NewRequest("POST", "/login", bytes.NewBuffer(loginJSON))
+	req.Header.Set("Content-Type", "application/json")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockUserService)
+	controller := NewUserController(mockService)
+
+	mockService.On("VerifyEmail", mock.Anything, "valid-token").Return(nil)
+	mockService.On("VerifyEmail", mock.Anything, "used-token").Return(e.NewBadRequestApiError(e.MsgInvalidVerificationToken))
+
+	router := setupRouter()
+	router.GET("/verify", controller.VerifyEmail)
+
+	req, _ := http.NewRequest("GET", "/verify?token=valid-token", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"email_verified":true}`, resp.Body.String())
+
+	// Test case: used token
+	req, _ = http.NewRequest("GET", "/verify?token=used-token", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// Test case: missing token
+	req, _ = http.NewRequest("GET", "/verify", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	mockService.AssertNumberOfCalls(t, "VerifyEmail", 2)
+}
+
+func TestPatchUser(t *testing.T) {
+	t.Parallel()
+
+	mockService := new(MockUserService)
+	controller := NewUserController(mockService)
+
+	patchedDto := &dto.UserDto{Id: 1, Name: "Johnny", UserName: "jdoe", Version: 3}
+	mergePatch := []byte(`{"name":"Johnny"}`)
+	jsonPatch := []byte(`[{"op":"replace","path":"/name","value":"Johnny"}]`)
+	mockService.On("PatchUser", mock.Anything, 1, 2, mergePatch, service.MergePatch).Return(patchedDto, nil)
+	mockService.On("PatchUser", mock.Anything, 1, 2, jsonPatch, service.JSONPatch).Return(patchedDto, nil)
+
+	router := setupRouter()
+	router.PATCH("/users/:id", controller.PatchUser)
+
+	for _, testCase := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{"application/merge-patch+json", mergePatch},
+		{"application/json", mergePatch}, // Plain JSON is read as a merge patch
+		{"application/json-patch+json; charset=utf-8", jsonPatch},
+	} {
+		req, _ := http.NewRequest("PATCH", "/users/1", bytes.NewBuffer(testCase.body))
+		req.Header.Set("Content-Type", testCase.contentType)
+		req.Header.Set("If-Match", `"2"`)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code, testCase.contentType)
+		assert.Equal(t, `"3"`, resp.Header().Get("ETag"))
+		assertNoSecrets(t, resp.Body.String())
+
+		var response dto.UserDto
+		err := json.Unmarshal(resp.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Johnny", response.Name)
+	}
+
+	mockService.AssertNumberOfCalls(t, "PatchUser", 3)
 }
@@ -1,47 +1,77 @@
 package db
 
 import (
-	userClient "user-api/client"
-	"user-api/model"
+	"time"
+	"user-api/config"
 
-	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/mysql"
 	log "github.com/sirupsen/logrus"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
-var (
-	db  *gorm.DB
-	err error
-)
-
-func init() {
-	// DB Connections Paramters
-	DBName := "ing-sw-3"
-	DBUser := "root"
-	DBPass := "mateo222"
-	//DBPass := os.Getenv("MVC_DB_PASS")
-	//DBHost := "mysql"
-	DBHost := "localhost"
-	DBPort := "3307"
-	// ------------------------
+// Open connects to the database described by the configuration, with whichever
+// driver it selects. Slow queries and errors are logged through logrus.
+func Open(dbConfig config.DatabaseConfig) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch dbConfig.Driver {
+	case config.DriverPostgres:
+		dialector = postgres.Open(dbConfig.DSN())
+	case config.DriverSQLite:
+		dialector = sqlite.Open(dbConfig.DSN())
+	default:
+		dialector = mysql.Open(dbConfig.DSN())
+	}
 
-	db, err = gorm.Open("mysql", DBUser+":"+DBPass+"@tcp("+DBHost+":"+DBPort+")/"+DBName+"?charset=utf8&parseTime=True")
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.New(log.StandardLogger(), logger.Config{
+			SlowThreshold:             200 * time.Millisecond,
+			LogLevel:                  logger.Warn,
+			IgnoreRecordNotFoundError: true,
+		}),
+	})
 
 	if err != nil {
 		log.Info("Connection Failed to Open")
-		log.Fatal(err)
-	} else {
-		log.Info("Connection Established")
+		return nil, err
 	}
+	log.WithField("driver", dbConfig.Driver).Info("Connection Established")
 
-	// We need to add all Clients that we build
-	userClient.Db = db
+	// SQLite allows a single writer, and every connection to ":memory:" would
+	// get its own empty database
+	if dbConfig.Driver == config.DriverSQLite {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
 
+	return db, nil
 }
 
-func StartDbEngine() {
-	// We need to migrate all classes model.
-	db.AutoMigrate(&model.User{})
+// Close releases the connections of the database.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// StartDbEngine brings the schema up to date with the migrations. It refuses to
+// start against a schema newer than the binary, which may have dropped or renamed
+// what this version relies on.
+func StartDbEngine(db *gorm.DB) error {
+	migrator := NewMigrator(db, Migrations)
+
+	applied, err := migrator.Up()
+	if err != nil {
+		return err
+	}
 
-	log.Info("Finishing Migration Database Tables")
+	log.Info("Database schema at version ", migrator.Latest(), ", ", len(applied), " migrations applied")
+	return nil
 }
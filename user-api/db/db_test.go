@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	userClient "user-api/client"
+	"user-api/config"
+	"user-api/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen_SQLiteFile(t *testing.T) {
+	dbConfig := config.DatabaseConfig{Driver: config.DriverSQLite, Name: filepath.Join(t.TempDir(), "users.db")}
+
+	db, err := Open(dbConfig)
+	assert.NoError(t, err)
+	assert.NoError(t, StartDbEngine(db))
+
+	inserted, err := userClient.NewUserClient(db).InsertUser(context.Background(), model.User{Name: "José", UserName: "jose", Email: "jose@example.com"}, model.AuditEntry{})
+	assert.NoError(t, err)
+	assert.NotZero(t, inserted.Id)
+	Close(db)
+
+	// The data outlives the connection
+	db, err = Open(dbConfig)
+	assert.NoError(t, err)
+	defer Close(db)
+	assert.NoError(t, StartDbEngine(db))
+
+	stored, err := userClient.NewUserClient(db).GetUserById(context.Background(), inserted.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "jose", stored.UserName)
+	for _, table := range []interface{}{&model.User{}, &model.RefreshToken{}, &model.OneTimeToken{}} {
+		assert.True(t, db.Migrator().HasTable(table))
+	}
+}
+
+func TestOpen_SQLiteMemory(t *testing.T) {
+	db, err := Open(config.DatabaseConfig{Driver: config.DriverSQLite, Name: ":memory:"})
+	assert.NoError(t, err)
+	defer Close(db)
+	assert.NoError(t, StartDbEngine(db))
+
+	// Every query has to see the schema created by the migration
+	users := userClient.NewUserClient(db)
+	for i := 0; i < 5; i++ {
+		page, err := users.GetUsers(context.Background(), userClient.UserQuery{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, page.Total)
+	}
+}
+
+func TestOpen_Unreachable(t *testing.T) {
+	_, err := Open(config.DatabaseConfig{Driver: config.DriverSQLite, Name: filepath.Join(t.TempDir(), "missing", "users.db")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"user-api/config"
+	"user-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func openMemory(t *testing.T) *gorm.DB {
+	db, err := Open(config.DatabaseConfig{Driver: config.DriverSQLite, Name: ":memory:"})
+	require.NoError(t, err)
+	t.Cleanup(func() { Close(db) })
+	return db
+}
+
+func TestMigrator_UpAndDown(t *testing.T) {
+	db := openMemory(t)
+	migrator := NewMigrator(db, Migrations)
+
+	applied, err := migrator.Up()
+	require.NoError(t, err)
+	assert.Len(t, applied, len(Migrations))
+	assert.True(t, db.Migrator().HasColumn("one_time_tokens", "email"))
+
+	version, _ := migrator.Version()
+	assert.Equal(t, migrator.Latest(), version)
+
+	// Nothing left to do
+	applied, err = migrator.Up()
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := migrator.Down()
+	require.NoError(t, err)
+	assert.Equal(t, "add_one_time_tokens_email", reverted.Name)
+	assert.False(t, db.Migrator().HasColumn("one_time_tokens", "email"))
+	assert.True(t, db.Migrator().HasIndex("one_time_tokens", "idx_one_time_tokens_user_id"))
+	assert.True(t, db.Migrator().HasTable("audit_entries"))
+	assert.True(t, db.Migrator().HasIndex("users", "idx_users_deleted_at"))
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	assert.Len(t, statuses, len(Migrations))
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[len(statuses)-1].AppliedAt)
+
+	// Every migration can be reverted and applied again
+	for {
+		reverted, err := migrator.Down()
+		require.NoError(t, err)
+		if reverted == nil {
+			break
+		}
+	}
+	assert.False(t, db.Migrator().HasTable("users"))
+	assert.False(t, db.Migrator().HasTable("refresh_tokens"))
+
+	applied, err = migrator.Up()
+	require.NoError(t, err)
+	assert.Len(t, applied, len(Migrations))
+}
+
+func TestMigrator_AdoptsAutoMigratedSchema(t *testing.T) {
+	db := openMemory(t)
+
+	// A database created by AutoMigrate, with rows older than the search text
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.RefreshToken{}, &model.OneTimeToken{}))
+	db.Create(&model.User{Name: "Lucía", LastName: "Fernández", UserName: "lfernandez", Email: "lucia@example.com"})
+
+	_, err := NewMigrator(db, Migrations).Up()
+	require.NoError(t, err)
+
+	var user model.User
+	require.NoError(t, db.Where("user_name = ?", "lfernandez").First(&user).Error)
+	assert.Equal(t, "lucia fernandez lfernandez lucia@example.com", user.SearchText)
+}
+
+func TestMigrator_SchemaTooNew(t *testing.T) {
+	db := openMemory(t)
+	require.NoError(t, StartDbEngine(db))
+	db.Create(&SchemaMigration{Version: 99, Name: "from_the_future"})
+
+	migrator := NewMigrator(db, Migrations)
+	assert.True(t, errors.Is(migrator.Check(), ErrSchemaTooNew))
+	_, err := migrator.Down()
+	assert.True(t, errors.Is(err, ErrSchemaTooNew))
+	assert.True(t, errors.Is(StartDbEngine(db), ErrSchemaTooNew))
+}
+
+func TestMigrator_FailedMigrationIsRolledBack(t *testing.T) {
+	db := openMemory(t)
+
+	migrator := NewMigrator(db, []Migration{
+		{
+			Version: 2,
+			Name:    "broken",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.Exec("CREATE TABLE broken (id integer)").Error; err != nil {
+					return err
+				}
+				return errors.New("broken migration")
+			},
+		},
+		{
+			Version: 1,
+			Name:    "works",
+			Up: func(tx *gorm.DB) error {
+				return tx.Exec("CREATE TABLE works (id integer)").Error
+			},
+		},
+	})
+
+	applied, err := migrator.Up()
+	assert.ErrorContains(t, err, "migration 2 broken")
+	assert.Len(t, applied, 1)
+
+	version, _ := migrator.Version()
+	assert.Equal(t, 1, version)
+	assert.True(t, db.Migrator().HasTable("works"))
+	assert.False(t, db.Migrator().HasTable("broken"))
+}
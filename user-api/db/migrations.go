@@ -0,0 +1,269 @@
+package db
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// Migrations is the history of the schema, oldest first. A released migration must
+// never change, mistakes are fixed with a new one. Migrations work on their own
+// copies of the models, as the ones in model describe only the latest schema.
+//
+// Up skips the tables, columns and indexes that already exist, so databases created
+// with AutoMigrate before there were migrations are adopted as they are.
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_users",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &usersV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("users")
+		},
+	},
+	{
+		Version: 2,
+		Name:    "create_refresh_tokens",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &refreshTokensV2{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("refresh_tokens")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create_one_time_tokens",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &oneTimeTokensV3{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("one_time_tokens")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add_users_email_verified",
+		Up: func(tx *gorm.DB) error {
+			return addColumn(tx, &usersV4{}, "EmailVerified")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&usersV4{}, "EmailVerified")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add_users_search_text",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumn(tx, &usersV5{}, "SearchText"); err != nil {
+				return err
+			}
+			return backfillSearchText(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&usersV5{}, "SearchText")
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add_users_deleted_at",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumn(tx, &usersV6{}, "DeletedAt"); err != nil {
+				return err
+			}
+			return addIndex(tx, &usersV6{}, "idx_users_deleted_at")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&usersV6{}, "idx_users_deleted_at"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&usersV6{}, "DeletedAt")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add_users_version",
+		Up: func(tx *gorm.DB) error {
+			return addColumn(tx, &usersV7{}, "Version")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&usersV7{}, "Version"); err != nil {
+				return err
+			}
+			// SQLite drops a column by rebuilding the table, which loses its indexes
+			return addIndex(tx, &usersV6{}, "idx_users_deleted_at")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create_audit_entries",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &auditEntriesV8{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("audit_entries")
+		},
+	},
+	{
+		// Tokens issued before have no email and can't verify one anymore
+		Version: 9,
+		Name:    "add_one_time_tokens_email",
+		Up: func(tx *gorm.DB) error {
+			return addColumn(tx, &oneTimeTokensV9{}, "Email")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&oneTimeTokensV9{}, "Email"); err != nil {
+				return err
+			}
+			// SQLite drops a column by rebuilding the table, which loses its indexes
+			return addIndex(tx, &oneTimeTokensV3{}, "idx_one_time_tokens_user_id")
+		},
+	},
+}
+
+// createTable creates the table of the model unless it exists.
+func createTable(tx *gorm.DB, table interface{}) error {
+	if tx.Migrator().HasTable(table) {
+		return nil
+	}
+	return tx.Migrator().CreateTable(table)
+}
+
+// addColumn adds the column of a field of the model unless it exists.
+func addColumn(tx *gorm.DB, table interface{}, field string) error {
+	if tx.Migrator().HasColumn(table, field) {
+		return nil
+	}
+	return tx.Migrator().AddColumn(table, field)
+}
+
+// addIndex creates an index declared by the model unless it exists.
+func addIndex(tx *gorm.DB, table interface{}, name string) error {
+	if tx.Migrator().HasIndex(table, name) {
+		return nil
+	}
+	return tx.Migrator().CreateIndex(table, name)
+}
+
+type usersV1 struct {
+	Id       int
+	Name     string `gorm:"type:varchar(300);not null"`
+	LastName string `gorm:"type:varchar(300);not null"`
+	UserName string `gorm:"type:varchar(200);not null;unique"`
+	Phone    int
+	Address  string `gorm:"type:varchar(200)"`
+	Password string `gorm:"type:varchar(500);not null"`
+	Email    string `gorm:"type:varchar(320);not null;unique"`
+	Type     bool   `gorm:"not null;default:false"`
+}
+
+func (usersV1) TableName() string { return "users" }
+
+type refreshTokensV2 struct {
+	Id        int
+	UserId    int        `gorm:"not null;index"`
+	TokenHash string     `gorm:"type:varchar(64);not null;unique"`
+	FamilyId  string     `gorm:"type:varchar(64);not null;index"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	RevokedAt *time.Time `gorm:""`
+	CreatedAt time.Time
+}
+
+func (refreshTokensV2) TableName() string { return "refresh_tokens" }
+
+type oneTimeTokensV3 struct {
+	Id        int
+	UserId    int        `gorm:"not null;index"`
+	Purpose   string     `gorm:"type:varchar(50);not null"`
+	TokenHash string     `gorm:"type:varchar(64);not null;unique"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time `gorm:""`
+	CreatedAt time.Time
+}
+
+func (oneTimeTokensV3) TableName() string { return "one_time_tokens" }
+
+type usersV4 struct {
+	EmailVerified bool `gorm:"not null;default:false"`
+}
+
+func (usersV4) TableName() string { return "users" }
+
+type usersV5 struct {
+	Id         int
+	Name       string
+	LastName   string
+	UserName   string
+	Email      string
+	SearchText string `gorm:"type:varchar(1200)"`
+}
+
+func (usersV5) TableName() string { return "users" }
+
+type usersV6 struct {
+	DeletedAt *time.Time `gorm:"index"`
+}
+
+func (usersV6) TableName() string { return "users" }
+
+type usersV7 struct {
+	Version int `gorm:"not null;default:1"`
+}
+
+func (usersV7) TableName() string { return "users" }
+
+type auditEntriesV8 struct {
+	Id        int
+	ActorId   *int      `gorm:"index"`
+	Action    string    `gorm:"type:varchar(20);not null;index"`
+	TargetId  int       `gorm:"not null;index"`
+	Changes   string    `gorm:"type:text"`
+	RequestId string    `gorm:"type:varchar(128)"`
+	CreatedAt time.Time `gorm:"not null;index"`
+}
+
+func (auditEntriesV8) TableName() string { return "audit_entries" }
+
+type oneTimeTokensV9 struct {
+	Email string `gorm:"type:varchar(320);not null;default:''"`
+}
+
+func (oneTimeTokensV9) TableName() string { return "one_time_tokens" }
+
+// backfillSearchText fills the search_text column of the users created before it
+// existed.
+func backfillSearchText(tx *gorm.DB) error {
+	var users []usersV5
+	if err := tx.Where("search_text IS NULL OR search_text = ''").Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := tx.Model(&user).UpdateColumn("search_text", user.searchText()).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchText is the search_text of the user as search.Document.Text wrote it when
+// the column was added: the normalized fields joined by spaces.
+func (user usersV5) searchText() string {
+	return strings.Join([]string{normalizeV5(user.Name), normalizeV5(user.LastName), normalizeV5(user.UserName), normalizeV5(user.Email)}, " ")
+}
+
+// normalizeV5 is search.Normalize as it was when the column was added.
+func normalizeV5(s string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err != nil {
+		stripped = s
+	}
+	return strings.ToLower(stripped)
+}
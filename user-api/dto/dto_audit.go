@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditQueryDto holds the query parameters of GET /user-api/audit. from and to are
+// RFC 3339 times; from is inclusive and to exclusive.
+type AuditQueryDto struct {
+	Limit    int       `form:"limit" json:"limit" validate:"min=0,max=100"`
+	Offset   int       `form:"offset" json:"offset" validate:"min=0"`
+	ActorId  int       `form:"actor_id" json:"actor_id" validate:"min=0"`
+	TargetId int       `form:"target_id" json:"target_id" validate:"min=0"`
+	Action   string    `form:"action" json:"action" validate:"omitempty,oneof=create update delete restore purge"`
+	From     time.Time `form:"from" json:"from"`
+	To       time.Time `form:"to" json:"to"`
+}
+
+// AuditChangeDto is the value of a field before and after a change.
+type AuditChangeDto struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditEntryDto is an entry of the audit log. ActorId is null for changes made
+// without logging in, like signing up.
+type AuditEntryDto struct {
+	Id        int              `json:"id"`
+	ActorId   *int             `json:"actor_id"`
+	Action    string           `json:"action"`
+	TargetId  int              `json:"target_id"`
+	Changes   []AuditChangeDto `json:"changes"`
+	RequestId string           `json:"request_id,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// AuditPageDto is the envelope of a page of the audit log.
+type AuditPageDto struct {
+	Items  []AuditEntryDto `json:"items"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+type LoginDto struct {
+	Login    string `json:"login"` // Username or email
+	Password string `json:"password"`
+}
+
+type TokenDto struct {
+	AccessToken      string    `json:"access_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type RefreshTokenDto struct {
+	RefreshToken string `json:"refresh_token"`
+}
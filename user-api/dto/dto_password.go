@@ -0,0 +1,15 @@
+package dto
+
+type PasswordChangeDto struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,password"`
+}
+
+type PasswordForgotDto struct {
+	Email string `json:"email" validate:"required,max=320,email"`
+}
+
+type PasswordResetDto struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,password"`
+}
@@ -1,15 +1,79 @@
 package dto
 
+// UserDto is the representation of a user sent to clients. It never carries secrets.
 type UserDto struct {
 	Id       int    `json:"id"`
 	Name     string `json:"name"`
 	LastName string `json:"last_name"`
 	UserName string `json:"username"`
-	Phone 	 int    `json:"phone"`
+	Phone    int    `json:"phone"`
 	Address  string `json:"address"`
-	Password string `json:"password"`
 	Email    string `json:"email"`
 	Type     bool   `json:"type"`
+
+	EmailVerified bool `json:"email_verified"`
+
+	// Version is sent in the ETag header, not in the body
+	Version int `json:"-"`
+}
+
+type UsersDto []UserDto
+
+// UsersPageDto is the envelope of a page of users.
+type UsersPageDto struct {
+	Items      UsersDto `json:"items"`
+	Total      int      `json:"total"`
+	Limit      int      `json:"limit"`
+	Offset     int      `json:"offset"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// UserListQueryDto holds the query parameters of GET /user-api/user. Sort is a field
+// name, prefixed with "-" for descending order.
+type UserListQueryDto struct {
+	Limit       int    `form:"limit" json:"limit" validate:"min=0,max=100"`
+	Offset      int    `form:"offset" json:"offset" validate:"min=0"`
+	Cursor      string `form:"cursor" json:"cursor"`
+	Sort        string `form:"sort" json:"sort" validate:"omitempty,oneof=id -id name -name last_name -last_name username -username email -email phone -phone address -address type -type"`
+	Name        string `form:"name" json:"name" validate:"max=300"`
+	EmailDomain string `form:"email_domain" json:"email_domain" validate:"max=255"`
+	Type        *bool  `form:"type" json:"type"`
+}
+
+// UserSearchQueryDto holds the query parameters of GET /user-api/user/search.
+type UserSearchQueryDto struct {
+	Q     string `form:"q" json:"q" validate:"required,max=200"`
+	Limit int    `form:"limit" json:"limit" validate:"min=0,max=100"`
+}
+
+// UserSearchHitDto is a user found by a search, with its relevance score.
+type UserSearchHitDto struct {
+	UserDto
+	Score int `json:"score"`
+}
+
+// UserSearchResultDto lists the users found by a search, best match first.
+type UserSearchResultDto struct {
+	Items []UserSearchHitDto `json:"items"`
+}
+
+// UserCreateDto is the body of a signup request. Length limits match the columns of model.User.
+type UserCreateDto struct {
+	Name     string `json:"name" validate:"required,max=300"`
+	LastName string `json:"last_name" validate:"required,max=300"`
+	UserName string `json:"username" validate:"required,min=3,max=200,username"`
+	Phone    int    `json:"phone" validate:"min=0"`
+	Address  string `json:"address" validate:"max=200"`
+	Password string `json:"password" validate:"required,password"`
+	Email    string `json:"email" validate:"required,max=320,email"`
 }
 
-type UsersDto []UserDto
\ No newline at end of file
+// UserUpdateDto is the body of a full update. The password has its own endpoint.
+type UserUpdateDto struct {
+	Name     string `json:"name" validate:"required,max=300"`
+	LastName string `json:"last_name" validate:"required,max=300"`
+	UserName string `json:"username" validate:"required,min=3,max=200,username"`
+	Phone    int    `json:"phone" validate:"min=0"`
+	Address  string `json:"address" validate:"max=200"`
+	Email    string `json:"email" validate:"required,max=320,email"`
+}
@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"context"
+	"time"
+	e "user-api/utils/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StartPurge runs purge right away and then every interval in the background,
+// until the returned stop function is called. Stopping also cancels the context of
+// a purge in progress.
+func StartPurge(interval time.Duration, purge func(ctx context.Context) (int, e.ApiError)) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			runPurge(ctx, purge)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return cancel
+}
+
+func runPurge(ctx context.Context, purge func(ctx context.Context) (int, e.ApiError)) {
+	purged, apiErr := purge(ctx)
+	if apiErr != nil {
+		log.Error("Error purging deleted users: ", apiErr.Error())
+		return
+	}
+	if purged > 0 {
+		log.Info("Deleted users purged: ", purged)
+	}
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+	e "user-api/utils/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartPurge(t *testing.T) {
+	runs := make(chan struct{}, 10)
+	stop := StartPurge(10*time.Millisecond, func(ctx context.Context) (int, e.ApiError) {
+		runs <- struct{}{}
+		return 1, nil
+	})
+
+	// Runs right away and then on every tick
+	for i := 0; i < 3; i++ {
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatal("purge didn't run")
+		}
+	}
+
+	stop()
+	time.Sleep(30 * time.Millisecond)
+	for len(runs) > 0 {
+		<-runs
+	}
+	time.Sleep(30 * time.Millisecond)
+	assert.Empty(t, runs)
+}
+
+func TestStartPurge_KeepsRunningAfterErrors(t *testing.T) {
+	runs := make(chan struct{}, 10)
+	stop := StartPurge(10*time.Millisecond, func(ctx context.Context) (int, e.ApiError) {
+		runs <- struct{}{}
+		return 0, e.NewInternalServerApiError(e.MsgCouldNotPurgeUsers, nil)
+	})
+	defer stop()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatal("purge stopped after an error")
+		}
+	}
+}
+
+func TestStartPurge_StopCancelsRunningPurge(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	stop := StartPurge(time.Hour, func(ctx context.Context) (int, e.ApiError) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return 0, nil
+	})
+
+	<-started
+	stop()
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("stop didn't cancel the purge")
+	}
+}
@@ -1,13 +1,145 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"os"
+	"time"
 	"user-api/app"
+	userClient "user-api/client"
+	"user-api/config"
+	userController "user-api/controller"
 	"user-api/db"
+	"user-api/jobs"
+	"user-api/notification"
+	"user-api/search"
+	"user-api/service"
+	"user-api/utils/token"
+
+	log "github.com/sirupsen/logrus"
 )
 
+// storage holds the clients of the configured storage.
+type storage struct {
+	users         userClient.UserClientInterface
+	refreshTokens userClient.RefreshTokenClientInterface
+	oneTimeTokens userClient.OneTimeTokenClientInterface
+	userSearch    search.Backend
+	audit         userClient.AuditClientInterface
+}
+
+func openStorage(cfg *config.Config) (storage, error) {
+	if cfg.Storage == config.StorageMemory {
+		log.Warn("Using in-memory storage, data will be lost on restart")
+		store := userClient.NewMemoryStore()
+		users := userClient.NewMemoryUserClient(store)
+		return storage{
+			users:         users,
+			refreshTokens: userClient.NewMemoryRefreshTokenClient(store),
+			oneTimeTokens: userClient.NewMemoryOneTimeTokenClient(store),
+			userSearch:    search.NewMemoryIndex(users.GetAllUsers),
+			audit:         userClient.NewMemoryAuditClient(store),
+		}, nil
+	}
+
+	conn, err := db.Open(cfg.Database)
+	if err != nil {
+		return storage{}, err
+	}
+	if err := db.StartDbEngine(conn); err != nil {
+		return storage{}, err
+	}
+
+	users := userClient.NewUserClient(conn)
+	var userSearch search.Backend = userClient.NewUserSearchClient(conn)
+	if cfg.Users.SearchBackend == "memory" {
+		userSearch = search.NewMemoryIndex(users.GetAllUsers)
+	}
+
+	return storage{
+		users:         users,
+		refreshTokens: userClient.NewRefreshTokenClient(conn),
+		oneTimeTokens: userClient.NewOneTimeTokenClient(conn),
+		userSearch:    userSearch,
+		audit:         userClient.NewAuditClient(conn),
+	}, nil
+}
+
+// signingSecret returns the configured JWT secret. Dev mode may run without one, and
+// then gets a random secret that lasts until the server stops.
+func signingSecret(cfg *config.Config) ([]byte, error) {
+	if cfg.Auth.JWTSecret != "" {
+		return []byte(cfg.Auth.JWTSecret), nil
+	}
+
+	secret, err := token.NewOpaque()
+	if err != nil {
+		return nil, err
+	}
+	log.Warn("JWT secret not set, using a random one: sessions end when the server stops")
+	return []byte(secret), nil
+}
+
 func main() {
-	db.StartDbEngine()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err == nil {
+		err = cfg.ValidateServer()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	app.ConfigureLogging(cfg.Log)
+	log.Debug("Configuration:\n", cfg)
+
+	clients, err := openStorage(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Services
+	settings := service.DefaultUserSettings()
+	settings.RequireVerifiedEmail = cfg.Users.RequireVerifiedEmail
+	settings.DeletedUserRetention = time.Duration(cfg.Users.DeletedRetention)
+
+	jwtSecret, err := signingSecret(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	signer := token.NewSigner(jwtSecret, time.Duration(cfg.Auth.AccessTokenTTL))
+
+	tokenService := service.NewTokenService(clients.users, clients.refreshTokens, signer, time.Duration(cfg.Auth.RefreshTokenTTL))
+	userService := service.NewUserService(
+		clients.users,
+		clients.refreshTokens,
+		clients.oneTimeTokens,
+		tokenService,
+		notification.Async{Handler: notification.Dispatcher{Notifier: notification.New(cfg.Notifications)}},
+		clients.userSearch,
+		settings,
+	)
+
+	jobs.StartPurge(time.Duration(cfg.Users.PurgeInterval), userService.PurgeDeletedUsers)
+
+	// Controllers
+	router := app.NewRouter(
+		cfg.Server,
+		signer,
+		userController.NewUserController(userService),
+		userController.NewTokenController(tokenService),
+		userController.NewAuditController(service.NewAuditService(clients.audit)),
+	)
 
-	app.StartRoute()
+	app.StartRoute(router, cfg.Server)
 
 }
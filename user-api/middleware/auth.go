@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"strings"
+	"user-api/audit"
+	e "user-api/utils/errors"
+	"user-api/utils/token"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// CallerKey is the gin.Context key under which the authenticated caller is stored.
+const CallerKey = "caller"
+
+// Route identifies a registered route by method and path pattern (as in c.FullPath()).
+type Route struct {
+	Method string
+	Path   string
+}
+
+// Caller is the identity extracted from a valid access token.
+type Caller struct {
+	UserId int
+	Role   string
+}
+
+func (c Caller) IsAdmin() bool {
+	return c.Role == token.RoleAdmin
+}
+
+// Authenticate validates the bearer token of every request except the public routes
+// with the signer, and stores the caller identity in the context.
+func Authenticate(signer *token.Signer, publicRoutes []Route) gin.HandlerFunc {
+	public := make(map[Route]bool, len(publicRoutes))
+	for _, route := range publicRoutes {
+		public[route] = true
+	}
+
+	return func(c *gin.Context) {
+		// Unknown routes are left to gin so they answer 404
+		if c.FullPath() == "" || public[Route{c.Request.Method, c.FullPath()}] {
+			c.Next()
+			return
+		}
+
+		header := c.GetHeader("Authorization")
+		tokenString, found := strings.CutPrefix(header, "Bearer ")
+		if !found || tokenString == "" {
+			abortUnauthorized(c)
+			return
+		}
+
+		claims, err := signer.Parse(tokenString)
+		if err != nil {
+			log.Debug("Invalid access token: ", err)
+			abortWithError(c, e.NewUnauthorizedApiError(e.MsgInvalidAccessToken))
+			return
+		}
+
+		c.Set(CallerKey, Caller{UserId: claims.UserId, Role: claims.Role})
+		// The services record the caller in the audit log
+		c.Request = c.Request.WithContext(audit.NewContext(c.Request.Context(), claims.UserId))
+		c.Next()
+	}
+}
+
+// GetCaller returns the authenticated caller stored by Authenticate.
+func GetCaller(c *gin.Context) (Caller, bool) {
+	value, exists := c.Get(CallerKey)
+	if !exists {
+		return Caller{}, false
+	}
+	caller, ok := value.(Caller)
+	return caller, ok
+}
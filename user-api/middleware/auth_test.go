@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-api/audit"
+	"user-api/utils/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSigner = token.NewSigner([]byte("test-secret"), 15*time.Minute)
+
+func setupRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Errors(), Authenticate(testSigner, []Route{{Method: http.MethodGet, Path: "/public"}}))
+
+	router.GET("/public", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/private", func(c *gin.Context) {
+		caller, _ := GetCaller(c)
+		c.JSON(http.StatusOK, caller)
+	})
+	return router
+}
+
+func TestAuthenticate_PublicRoute(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/public", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestAuthenticate_MissingToken(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/private", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "unauthorized_scopes")
+}
+
+func TestAuthenticate_InvalidToken(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/private", nil)
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestAuthenticate_OtherSecret(t *testing.T) {
+	router := setupRouter()
+
+	accessToken, _, _ := token.NewSigner([]byte("other-secret"), 15*time.Minute).Generate(7, true)
+
+	req, _ := http.NewRequest("GET", "/private", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestAuthenticate_ValidToken(t *testing.T) {
+	router := setupRouter()
+
+	accessToken, _, _ := testSigner.Generate(7, true)
+
+	req, _ := http.NewRequest("GET", "/private", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"UserId":7,"Role":"admin"}`, resp.Body.String())
+}
+
+func TestAuthenticate_SetsAuditActor(t *testing.T) {
+	router := setupRouter()
+	router.GET("/actor", func(c *gin.Context) {
+		actorId, ok := audit.ActorFromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"actor_id": actorId, "ok": ok})
+	})
+
+	accessToken, _, _ := testSigner.Generate(7, false)
+
+	req, _ := http.NewRequest("GET", "/actor", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"actor_id":7,"ok":true}`, resp.Body.String())
+}
+
+func TestAuthenticate_UnknownRoute(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/missing", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
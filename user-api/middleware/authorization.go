@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"strconv"
+	e "user-api/utils/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin only lets through callers with the admin role (model.User.Type = true).
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, ok := GetCaller(c)
+		if !ok {
+			abortUnauthorized(c)
+			return
+		}
+		if !caller.IsAdmin() {
+			abortForbidden(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSelfOrAdmin lets through admins and callers whose id matches the given path param.
+func RequireSelfOrAdmin(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, ok := GetCaller(c)
+		if !ok {
+			abortUnauthorized(c)
+			return
+		}
+		if caller.IsAdmin() {
+			c.Next()
+			return
+		}
+		id, err := strconv.Atoi(c.Param(param))
+		if err != nil || id != caller.UserId {
+			abortForbidden(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+func abortUnauthorized(c *gin.Context) {
+	abortWithError(c, e.NewUnauthorizedApiError(e.MsgAccessTokenMissing))
+}
+
+func abortForbidden(c *gin.Context) {
+	abortWithError(c, e.NewForbiddenApiError(e.MsgForbidden))
+}
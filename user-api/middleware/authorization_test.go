@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAuthorizationRouter(caller *Caller) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Errors(), func(c *gin.Context) {
+		if caller != nil {
+			c.Set(CallerKey, *caller)
+		}
+	})
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/users", RequireAdmin(), ok)
+	router.GET("/users/:id", RequireSelfOrAdmin("id"), ok)
+	return router
+}
+
+func doRequest(router *gin.Engine, path string) int {
+	req, _ := http.NewRequest("GET", path, nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp.Code
+}
+
+func TestRequireAdmin(t *testing.T) {
+	admin := setupAuthorizationRouter(&Caller{UserId: 1, Role: "admin"})
+	assert.Equal(t, http.StatusOK, doRequest(admin, "/users"))
+
+	user := setupAuthorizationRouter(&Caller{UserId: 2, Role: "user"})
+	assert.Equal(t, http.StatusForbidden, doRequest(user, "/users"))
+
+	anonymous := setupAuthorizationRouter(nil)
+	assert.Equal(t, http.StatusUnauthorized, doRequest(anonymous, "/users"))
+}
+
+func TestRequireSelfOrAdmin(t *testing.T) {
+	admin := setupAuthorizationRouter(&Caller{UserId: 1, Role: "admin"})
+	assert.Equal(t, http.StatusOK, doRequest(admin, "/users/5"))
+
+	user := setupAuthorizationRouter(&Caller{UserId: 2, Role: "user"})
+	assert.Equal(t, http.StatusOK, doRequest(user, "/users/2"))
+	assert.Equal(t, http.StatusForbidden, doRequest(user, "/users/5"))
+	assert.Equal(t, http.StatusForbidden, doRequest(user, "/users/invalid"))
+
+	anonymous := setupAuthorizationRouter(nil)
+	assert.Equal(t, http.StatusUnauthorized, doRequest(anonymous, "/users/2"))
+}
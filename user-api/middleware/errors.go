@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	e "user-api/utils/errors"
+	"user-api/utils/i18n"
+	"user-api/utils/requestid"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// Errors renders the error a handler reported with c.Error. An ApiError is sent
+// with its own status, any other error as a 500, and both in the utils/errors JSON
+// shape with the id of the request, or as RFC 9457 problem details when the Accept
+// header prefers them. Messages are translated to the language of Accept-Language.
+// Handlers that fail only call c.Error and return.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		renderError(c, c.Errors.Last().Err)
+	}
+}
+
+// Recovery turns a panic into a 500 in the same shape as any other error.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		log.Error("Panic serving ", c.Request.Method, " ", c.Request.URL.Path, ": ", recovered)
+		renderError(c, e.NewInternalServerApiError(e.MsgInternalError, nil))
+	})
+}
+
+// abortWithError stops the chain and leaves the error to Errors.
+func abortWithError(c *gin.Context, apiErr e.ApiError) {
+	c.Error(apiErr)
+	c.Abort()
+}
+
+func renderError(c *gin.Context, err error) {
+	apiErr, ok := err.(e.ApiError)
+	if !ok {
+		log.Error("Error serving ", c.Request.Method, " ", c.Request.URL.Path, ": ", err)
+		apiErr = e.NewInternalServerApiError(e.MsgInternalError, nil)
+	}
+
+	language := i18n.FromAcceptLanguage(c.GetHeader("Accept-Language"))
+	apiErr = e.Localize(apiErr, language)
+	c.Header("Content-Language", string(language))
+	c.Writer.Header().Add("Vary", "Accept, Accept-Language")
+
+	requestId := requestid.FromContext(c.Request.Context())
+	if e.PrefersProblemDetails(c.GetHeader("Accept")) {
+		// gin keeps a Content-Type that is already set when rendering JSON
+		c.Header("Content-Type", e.ProblemMediaType)
+		c.AbortWithStatusJSON(apiErr.Status(), e.NewProblemDetails(apiErr, c.Request.URL.Path, requestId))
+		return
+	}
+	c.AbortWithStatusJSON(apiErr.Status(), e.NewResponseBody(apiErr, requestId))
+}
@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	e "user-api/utils/errors"
+	"user-api/utils/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupErrorsRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(), Recovery(), Errors())
+
+	router.GET("/api-error", func(c *gin.Context) {
+		c.Error(e.NewNotFoundApiError(e.MsgUserNotFound))
+	})
+	router.GET("/plain-error", func(c *gin.Context) {
+		c.Error(errors.New("connection reset by peer"))
+	})
+	router.GET("/invalid", func(c *gin.Context) {
+		c.Error(e.NewValidationApiError(e.MsgInvalidData, "validation_error", e.CauseList{
+			validation.FieldError{Field: "email", Rule: "email", Message: "email debe ser un email válido"},
+			"causa sin campo",
+		}))
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	router.GET("/ok", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func doErrorRequest(router *gin.Engine, path string, requestId string) (*httptest.ResponseRecorder, e.ResponseBody) {
+	req, _ := http.NewRequest("GET", path, nil)
+	if requestId != "" {
+		req.Header.Set("X-Request-ID", requestId)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var body e.ResponseBody
+	json.Unmarshal(resp.Body.Bytes(), &body)
+	return resp, body
+}
+
+func TestErrors_ApiError(t *testing.T) {
+	resp, body := doErrorRequest(setupErrorsRouter(), "/api-error", "abc-123")
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, "Usuario no encontrado", body.Message)
+	assert.Equal(t, "not_found", body.Error)
+	assert.Equal(t, http.StatusNotFound, body.Status)
+	assert.Equal(t, "abc-123", body.RequestId)
+	assert.Equal(t, "abc-123", resp.Header().Get("X-Request-ID"))
+}
+
+func TestErrors_PlainError(t *testing.T) {
+	resp, body := doErrorRequest(setupErrorsRouter(), "/plain-error", "")
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, "internal_server_error", body.Error)
+	assert.NotContains(t, resp.Body.String(), "connection reset")
+	// A request id is made up when the caller doesn't send one
+	assert.NotEmpty(t, body.RequestId)
+	assert.Equal(t, resp.Header().Get("X-Request-ID"), body.RequestId)
+}
+
+func TestErrors_Panic(t *testing.T) {
+	resp, body := doErrorRequest(setupErrorsRouter(), "/panic", "")
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, "internal_server_error", body.Error)
+	assert.NotEmpty(t, body.RequestId)
+}
+
+func TestErrors_NoError(t *testing.T) {
+	resp, _ := doErrorRequest(setupErrorsRouter(), "/ok", "")
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Empty(t, resp.Body.String())
+}
+
+func TestRequestID_RejectsUnusableIds(t *testing.T) {
+	resp, body := doErrorRequest(setupErrorsRouter(), "/api-error", "bad id\twith spaces")
+
+	assert.NotEqual(t, "bad id\twith spaces", body.RequestId)
+	assert.Len(t, body.RequestId, 32)
+	assert.Equal(t, body.RequestId, resp.Header().Get("X-Request-ID"))
+}
+
+func doProblemRequest(router *gin.Engine, path string, accept string) (*httptest.ResponseRecorder, e.ProblemDetails) {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("Accept", accept)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var problem e.ProblemDetails
+	json.Unmarshal(resp.Body.Bytes(), &problem)
+	return resp, problem
+}
+
+func TestErrors_ProblemDetails(t *testing.T) {
+	resp, problem := doProblemRequest(setupErrorsRouter(), "/api-error", "application/problem+json")
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "urn:user-api:problem:not-found", problem.Type)
+	assert.Equal(t, "Not Found", problem.Title)
+	assert.Equal(t, http.StatusNotFound, problem.Status)
+	assert.Equal(t, "Usuario no encontrado", problem.Detail)
+	assert.Equal(t, "/api-error", problem.Instance)
+	assert.Equal(t, "not_found", problem.Code)
+	assert.Equal(t, resp.Header().Get("X-Request-ID"), problem.RequestId)
+	assert.Empty(t, problem.InvalidParams)
+}
+
+func TestErrors_ProblemDetailsInvalidParams(t *testing.T) {
+	resp, problem := doProblemRequest(setupErrorsRouter(), "/invalid", "application/problem+json")
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "urn:user-api:problem:validation-error", problem.Type)
+	// Causes that don't name a parameter are left out
+	assert.Equal(t, []e.InvalidParam{{Name: "email", Reason: "email debe ser un email válido"}}, problem.InvalidParams)
+}
+
+func TestErrors_ProblemDetailsPanic(t *testing.T) {
+	resp, problem := doProblemRequest(setupErrorsRouter(), "/panic", "application/problem+json")
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, "urn:user-api:problem:internal-error", problem.Type)
+}
+
+func TestErrors_AcceptNegotiation(t *testing.T) {
+	tests := []struct {
+		accept  string
+		problem bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"application/problem+json", true},
+		{"application/json, application/problem+json", true},
+		{"application/problem+json;q=0.5, application/json", false},
+		{"application/problem+json, application/json;q=0.9", true},
+		{"application/problem+json;q=0", false},
+		{"text/html, application/problem+json;q=0.1", true},
+	}
+
+	for _, test := range tests {
+		resp, _ := doProblemRequest(setupErrorsRouter(), "/api-error", test.accept)
+
+		if test.problem {
+			assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"), test.accept)
+		} else {
+			assert.Contains(t, resp.Header().Get("Content-Type"), "application/json", test.accept)
+		}
+	}
+}
+
+func doLocalizedRequest(router *gin.Engine, path string, language string) (*httptest.ResponseRecorder, e.ResponseBody) {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("Accept-Language", language)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var body e.ResponseBody
+	json.Unmarshal(resp.Body.Bytes(), &body)
+	return resp, body
+}
+
+func TestErrors_English(t *testing.T) {
+	resp, body := doLocalizedRequest(setupErrorsRouter(), "/api-error", "en-US,en;q=0.9")
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, "User not found", body.Message)
+	assert.Equal(t, "not_found", body.Error)
+	assert.Equal(t, "en", resp.Header().Get("Content-Language"))
+}
+
+func TestErrors_SpanishByDefault(t *testing.T) {
+	resp, body := doLocalizedRequest(setupErrorsRouter(), "/api-error", "fr-FR")
+
+	assert.Equal(t, "Usuario no encontrado", body.Message)
+	assert.Equal(t, "es", resp.Header().Get("Content-Language"))
+}
+
+func TestErrors_EnglishValidationCauses(t *testing.T) {
+	_, body := doLocalizedRequest(setupErrorsRouter(), "/invalid", "en")
+
+	assert.Equal(t, "Invalid data", body.Message)
+	cause := body.Cause[0].(map[string]interface{})
+	assert.Equal(t, "email", cause["field"])
+	assert.Equal(t, "Must be a valid email", cause["message"])
+	// Causes that aren't translatable are sent as they are
+	assert.Equal(t, "causa sin campo", body.Cause[1])
+}
+
+func TestErrors_EnglishProblemDetails(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/invalid", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	req.Header.Set("Accept-Language", "en")
+	resp := httptest.NewRecorder()
+	setupErrorsRouter().ServeHTTP(resp, req)
+
+	var problem e.ProblemDetails
+	json.Unmarshal(resp.Body.Bytes(), &problem)
+	assert.Equal(t, "Invalid data", problem.Detail)
+	assert.Equal(t, []e.InvalidParam{{Name: "email", Reason: "Must be a valid email"}}, problem.InvalidParams)
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"user-api/utils/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestID tags every request with an id, the one sent in X-Request-ID if it's
+// usable or a new one. The id is echoed in the response header and carried by the
+// request context, so errors and logs can be matched with the request.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		c.Header(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Next()
+	}
+}
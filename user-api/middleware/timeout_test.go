@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Timeout(20 * time.Millisecond))
+
+	var handlerCtx context.Context
+	router.GET("/slow", func(c *gin.Context) {
+		handlerCtx = c.Request.Context()
+		deadline, ok := handlerCtx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(20*time.Millisecond), deadline, 20*time.Millisecond)
+
+		select {
+		case <-handlerCtx.Done():
+			c.Status(http.StatusServiceUnavailable)
+		case <-time.After(time.Second):
+			c.Status(http.StatusOK)
+		}
+	})
+
+	req, _ := http.NewRequest("GET", "/slow", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.ErrorIs(t, handlerCtx.Err(), context.DeadlineExceeded)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+	"user-api/app"
+	"user-api/config"
+	"user-api/db"
+)
+
+const migrateUsage = "usage: user-api migrate up|down|status [flags]"
+
+// runMigrate implements the migrate subcommand:
+//
+//	up      applies every pending migration
+//	down    reverts the newest applied migration
+//	status  lists the migrations and whether they are applied
+//
+// The flags are the same as the server's, to find the database.
+func runMigrate(args []string, out io.Writer) error {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "down" && args[0] != "status") {
+		return errors.New(migrateUsage)
+	}
+	action := args[0]
+
+	cfg, err := config.Load(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if cfg.Storage != config.StorageDB {
+		return errors.New("migrations need the db storage")
+	}
+	app.ConfigureLogging(cfg.Log)
+
+	conn, err := db.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close(conn)
+	migrator := db.NewMigrator(conn, db.Migrations)
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "nothing to apply")
+		}
+	case "down":
+		reverted, err := migrator.Down()
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			fmt.Fprintln(out, "nothing to revert")
+		} else {
+			fmt.Fprintf(out, "reverted %d %s\n", reverted.Version, reverted.Name)
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		w.Flush()
+		return migrator.Check()
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+)
+
+// AuditEntry records a change to a user: who made it, in which request and the
+// value of every changed field before and after.
+type AuditEntry struct {
+	Id        int           `gorm:"primaryKey"`
+	ActorId   *int          `gorm:"index"` // Nil when nobody was logged in, as when signing up
+	Action    string        `gorm:"type:varchar(20);not null;index"`
+	TargetId  int           `gorm:"not null;index"`
+	Changes   []AuditChange `gorm:"type:text;serializer:json"`
+	RequestId string        `gorm:"type:varchar(128)"`
+	CreatedAt time.Time     `gorm:"not null;index"`
+}
+
+// AuditChange is the JSON value of a field before and after a change. Before is
+// empty for a created user and After for a deleted one.
+type AuditChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
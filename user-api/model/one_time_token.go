@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+)
+
+// OneTimeToken is a single-use, expiring token sent to the user out of band.
+type OneTimeToken struct {
+	Id        int        `gorm:"primaryKey"`
+	UserId    int        `gorm:"not null;index"`
+	Purpose   string     `gorm:"type:varchar(50);not null"`
+	TokenHash string     `gorm:"type:varchar(64);not null;unique"`
+	Email     string     `gorm:"type:varchar(320);not null;default:''"` // Address the token was sent to
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time `gorm:""`
+	CreatedAt time.Time
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+type RefreshToken struct {
+	Id        int        `gorm:"primaryKey"`
+	UserId    int        `gorm:"not null;index"`
+	TokenHash string     `gorm:"type:varchar(64);not null;unique"`
+	FamilyId  string     `gorm:"type:varchar(64);not null;index"` // Shared by every token rotated from the same login
+	ExpiresAt time.Time  `gorm:"not null"`
+	RevokedAt *time.Time `gorm:""`
+	CreatedAt time.Time
+}
@@ -1,5 +1,7 @@
 package model
 
+import "gorm.io/gorm"
+
 type User struct {
 	Id       int    `gorm:"primaryKey"`
 	Name     string `gorm:"type:varchar(300);not null"`
@@ -10,6 +12,19 @@ type User struct {
 	Password string `gorm:"type:varchar(500);not null"`
 	Email    string `gorm:"type:varchar(320);not null;unique"`
 	Type     bool   `gorm:"not null;default:false"`
+
+	EmailVerified bool `gorm:"not null;default:false"`
+
+	// Incremented on every update. Writes carry the version they read and fail if
+	// it changed meanwhile, so concurrent edits don't overwrite each other.
+	Version int `gorm:"not null;default:1"`
+
+	// Name, last name, username and email without accents, for search
+	SearchText string `gorm:"type:varchar(1200)"`
+
+	// Set when the user is deleted. Deleted users are hidden from every query until
+	// they are restored or purged.
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
 type Users []User
@@ -0,0 +1,33 @@
+package notification
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// deliveryTimeout bounds the delivery of an event in the background: an email and
+// an SMS, each within sendTimeout.
+const deliveryTimeout = 2 * sendTimeout
+
+// Async hands events to Handler in the background, so that a slow relay doesn't
+// hold the request that triggered them nor use up its deadline. Delivery runs on
+// a context that keeps the values of the request, such as its id, but not its
+// cancellation, and is bounded by deliveryTimeout. Handle always succeeds; delivery
+// failures are logged.
+type Async struct {
+	Handler EventHandler
+}
+
+func (a Async) Handle(ctx context.Context, event Event) error {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		defer cancel()
+
+		if err := a.Handler.Handle(ctx, event); err != nil {
+			log.WithField("event", event.Kind).Error("Error sending notification: ", err)
+		}
+	}()
+	return nil
+}
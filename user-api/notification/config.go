@@ -0,0 +1,50 @@
+package notification
+
+import (
+	"strconv"
+	"user-api/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// New builds the notifiers chosen for each channel in the configuration. A channel
+// without one, which only dev mode allows, writes to the log.
+func New(cfg config.NotificationConfig) Notifier {
+	var file *FileNotifier
+	fileNotifier := func() Notifier {
+		if file == nil {
+			file = &FileNotifier{Path: cfg.File}
+		}
+		return file
+	}
+
+	var email Notifier = LogNotifier{}
+	switch cfg.Email {
+	case config.NotifierFile:
+		email = fileNotifier()
+	case config.NotifierSMTP:
+		email = SMTPNotifier{
+			Host:     cfg.SMTP.Host,
+			Port:     strconv.Itoa(cfg.SMTP.Port),
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+		}
+	}
+
+	var sms Notifier = LogNotifier{}
+	switch cfg.SMS {
+	case config.NotifierFile:
+		sms = fileNotifier()
+	case config.NotifierWebhook:
+		sms = SMSWebhookNotifier{
+			URL:   cfg.SMSWebhook.URL,
+			Token: cfg.SMSWebhook.Token,
+		}
+	case config.NotifierNone:
+		sms = DiscardNotifier{}
+	}
+
+	log.WithFields(log.Fields{"email": cfg.Email, "sms": cfg.SMS}).Info("Notifications configured")
+	return ChannelNotifier{Email: email, SMS: sms}
+}
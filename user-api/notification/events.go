@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"user-api/model"
+)
+
+type EventKind string
+
+const (
+	EventUserRegistered             EventKind = "user_registered"
+	EventPasswordResetRequested     EventKind = "password_reset_requested"
+	EventEmailVerificationRequested EventKind = "email_verification_requested"
+)
+
+// Event is something that happened to a user and may need to be told to them.
+type Event struct {
+	Kind  EventKind
+	User  model.User
+	Token string // Raw one-time token, for the events that carry one
+}
+
+type UnknownEventError struct {
+	Kind EventKind
+}
+
+func (e *UnknownEventError) Error() string {
+	return "unknown notification event " + string(e.Kind)
+}
+
+// EventHandler reacts to user events.
+type EventHandler interface {
+	Handle(ctx context.Context, event Event) error
+}
+
+// Dispatcher renders the template of each event and sends it through the notifier:
+// always by email, and also by SMS when the template has an SMS version and the
+// user has a phone.
+type Dispatcher struct {
+	Notifier Notifier
+}
+
+func (d Dispatcher) Handle(ctx context.Context, event Event) error {
+	subject, email, sms, err := Render(event.Kind, TemplateData{
+		Name:     event.User.Name,
+		UserName: event.User.UserName,
+		Token:    event.Token,
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if event.User.Email != "" {
+		errs = append(errs, d.Notifier.Send(ctx, Message{Channel: ChannelEmail, To: event.User.Email, Subject: subject, Body: email}))
+	}
+	if sms != "" && event.User.Phone != 0 {
+		errs = append(errs, d.Notifier.Send(ctx, Message{Channel: ChannelSMS, To: strconv.Itoa(event.User.Phone), Subject: subject, Body: sms}))
+	}
+	return errors.Join(errs...)
+}
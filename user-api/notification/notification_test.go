@@ -0,0 +1,275 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"user-api/config"
+	"user-api/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+func TestRender(t *testing.T) {
+	subject, email, sms, err := Render(EventPasswordResetRequested, TemplateData{Name: "John", UserName: "jdoe", Token: "abc123"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Restablecer contraseña", subject)
+	assert.Contains(t, email, "Hola John")
+	assert.Contains(t, email, "abc123")
+	assert.Contains(t, sms, "abc123")
+
+	_, _, sms, err = Render(EventUserRegistered, TemplateData{Name: "John"})
+	assert.NoError(t, err)
+	assert.Empty(t, sms)
+
+	_, _, _, err = Render("unknown", TemplateData{})
+	assert.Error(t, err)
+}
+
+func TestDispatcher(t *testing.T) {
+	notifier := &MemoryNotifier{}
+	dispatcher := Dispatcher{Notifier: notifier}
+
+	user := model.User{Name: "John", Email: "jdoe@example.com", Phone: 1155554444}
+
+	// Test case: email only template
+	err := dispatcher.Handle(ctx, Event{Kind: EventUserRegistered, User: user})
+	assert.NoError(t, err)
+	assert.Len(t, notifier.Messages(), 1)
+	assert.Equal(t, ChannelEmail, notifier.Messages()[0].Channel)
+
+	// Test case: email and SMS template
+	err = dispatcher.Handle(ctx, Event{Kind: EventPasswordResetRequested, User: user, Token: "abc123"})
+	assert.NoError(t, err)
+	messages := notifier.Messages()
+	assert.Len(t, messages, 3)
+	assert.Equal(t, ChannelSMS, messages[2].Channel)
+	assert.Equal(t, "1155554444", messages[2].To)
+}
+
+func TestChannelNotifier(t *testing.T) {
+	email := &MemoryNotifier{}
+	notifier := ChannelNotifier{Email: email}
+
+	assert.NoError(t, notifier.Send(ctx, Message{Channel: ChannelEmail, To: "jdoe@example.com"}))
+	assert.Len(t, email.Messages(), 1)
+
+	// Test case: channel without notifier
+	assert.Error(t, notifier.Send(ctx, Message{Channel: ChannelSMS, To: "1155554444"}))
+}
+
+func TestFileNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.log")
+	notifier := &FileNotifier{Path: path}
+
+	assert.NoError(t, notifier.Send(ctx, Message{Channel: ChannelEmail, To: "jdoe@example.com", Subject: "Hola", Body: "first"}))
+	assert.NoError(t, notifier.Send(ctx, Message{Channel: ChannelEmail, To: "jdoe@example.com", Subject: "Hola", Body: "second"}))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "To: jdoe@example.com")
+	assert.Equal(t, 2, strings.Count(string(content), "Subject: Hola"))
+}
+
+func TestSMSWebhookNotifier(t *testing.T) {
+	var received smsPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	notifier := SMSWebhookNotifier{URL: server.URL, Token: "secret"}
+	err := notifier.Send(ctx, Message{Channel: ChannelSMS, To: "1155554444", Body: "hola"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, smsPayload{To: "1155554444", Body: "hola"}, received)
+
+	// Test case: gateway error
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	notifier = SMSWebhookNotifier{URL: failing.URL}
+	assert.Error(t, notifier.Send(ctx, Message{Channel: ChannelSMS, To: "1155554444", Body: "hola"}))
+}
+
+// serveSMTP answers a single SMTP session on a local port with canned replies and
+// returns its address and the lines the client sent.
+func serveSMTP(t *testing.T) (string, <-chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		reader := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 localhost ready\r\n")
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+
+			switch {
+			case inData:
+				if line == "." {
+					inData = false
+					fmt.Fprint(conn, "250 queued\r\n")
+				}
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				fmt.Fprint(conn, "354 go ahead\r\n")
+			case strings.HasPrefix(line, "QUIT"):
+				fmt.Fprint(conn, "221 bye\r\n")
+				received <- lines
+				return
+			default:
+				fmt.Fprint(conn, "250 ok\r\n")
+			}
+		}
+		received <- lines
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, received := serveSMTP(t)
+	host, port, _ := net.SplitHostPort(addr)
+	notifier := SMTPNotifier{Host: host, Port: port, From: "no-reply@example.com"}
+
+	err := notifier.Send(ctx, Message{Channel: ChannelEmail, To: "jdoe@example.com", Subject: "Hola", Body: "abc123"})
+
+	assert.NoError(t, err)
+	lines := strings.Join(<-received, "\n")
+	assert.Contains(t, lines, "MAIL FROM:<no-reply@example.com>")
+	assert.Contains(t, lines, "RCPT TO:<jdoe@example.com>")
+	assert.Contains(t, lines, "abc123")
+}
+
+func TestSMTPNotifier_Deadline(t *testing.T) {
+	// A server that accepts the connection and never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	notifier := SMTPNotifier{Host: host, Port: port, From: "no-reply@example.com"}
+
+	deadlineCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = notifier.Send(deadlineCtx, Message{Channel: ChannelEmail, To: "jdoe@example.com"})
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestSMTPNotifierFormat(t *testing.T) {
+	notifier := SMTPNotifier{From: "no-reply@example.com"}
+
+	raw := string(notifier.format(Message{To: "jdoe@example.com", Subject: "Hola\r\nBcc: evil@example.com", Body: "line1\nline2"}))
+
+	assert.Contains(t, raw, "Subject: HolaBcc: evil@example.com\r\n")
+	assert.NotContains(t, raw, "\r\nBcc:")
+	assert.Contains(t, raw, "line1\r\nline2")
+
+	// Test case: non-ASCII subjects are encoded
+	raw = string(notifier.format(Message{To: "jdoe@example.com", Subject: "Restablecer contraseña"}))
+	assert.Contains(t, raw, "Subject: =?utf-8?q?Restablecer_contrase=C3=B1a?=\r\n")
+	decoded, err := new(mime.WordDecoder).DecodeHeader("=?utf-8?q?Restablecer_contrase=C3=B1a?=")
+	assert.NoError(t, err)
+	assert.Equal(t, "Restablecer contraseña", decoded)
+}
+
+func TestNew(t *testing.T) {
+	cfg := config.DefaultNotifications()
+	cfg.Email = config.NotifierSMTP
+	cfg.SMTP = config.SMTPConfig{Host: "smtp.example.com", Port: 2525, From: "no-reply@example.com"}
+	cfg.SMS = config.NotifierFile
+	cfg.File = filepath.Join(t.TempDir(), "notifications.log")
+
+	notifier := New(cfg).(ChannelNotifier)
+
+	assert.Equal(t, SMTPNotifier{Host: "smtp.example.com", Port: "2525", From: "no-reply@example.com"}, notifier.Email)
+	assert.Equal(t, cfg.File, notifier.SMS.(*FileNotifier).Path)
+}
+
+func TestNew_Unset(t *testing.T) {
+	// Only dev mode gets this far without notifiers
+	notifier := New(config.DefaultNotifications()).(ChannelNotifier)
+	assert.Equal(t, LogNotifier{}, notifier.Email)
+	assert.Equal(t, LogNotifier{}, notifier.SMS)
+
+	cfg := config.DefaultNotifications()
+	cfg.SMS = config.NotifierNone
+	notifier = New(cfg).(ChannelNotifier)
+	assert.NoError(t, notifier.Send(ctx, Message{Channel: ChannelSMS, To: "1155554444"}))
+	assert.Equal(t, DiscardNotifier{}, notifier.SMS)
+}
+
+// blockingHandler records the context of each event and holds it until released.
+type blockingHandler struct {
+	contexts chan context.Context
+	release  chan struct{}
+}
+
+func (h blockingHandler) Handle(ctx context.Context, event Event) error {
+	h.contexts <- ctx
+	<-h.release
+	return nil
+}
+
+func TestAsync(t *testing.T) {
+	handler := blockingHandler{contexts: make(chan context.Context, 1), release: make(chan struct{})}
+	defer close(handler.release)
+
+	requestCtx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	start := time.Now()
+	assert.NoError(t, Async{Handler: handler}.Handle(requestCtx, Event{Kind: EventUserRegistered}))
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	cancel()
+
+	// Delivery outlives the request, with a deadline of its own
+	deliveryCtx := <-handler.contexts
+	assert.NoError(t, deliveryCtx.Err())
+	deadline, ok := deliveryCtx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(deliveryTimeout), deadline, time.Second)
+}
@@ -0,0 +1,113 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+)
+
+// Message is a notification addressed to a single recipient.
+type Message struct {
+	Channel Channel
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users out of band. Send gives up when ctx is done.
+type Notifier interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// ChannelNotifier routes every message to the notifier of its channel.
+type ChannelNotifier struct {
+	Email Notifier
+	SMS   Notifier
+}
+
+func (n ChannelNotifier) Send(ctx context.Context, message Message) error {
+	var notifier Notifier
+	switch message.Channel {
+	case ChannelEmail:
+		notifier = n.Email
+	case ChannelSMS:
+		notifier = n.SMS
+	}
+	if notifier == nil {
+		return fmt.Errorf("no notifier configured for channel %q", message.Channel)
+	}
+	return notifier.Send(ctx, message)
+}
+
+// LogNotifier writes every message to the application log, one-time tokens included.
+// Only for local use.
+type LogNotifier struct{}
+
+func (LogNotifier) Send(ctx context.Context, message Message) error {
+	log.WithFields(log.Fields{
+		"channel": message.Channel,
+		"to":      message.To,
+		"subject": message.Subject,
+	}).Info(message.Body)
+	return nil
+}
+
+// DiscardNotifier drops every message, for channels the deployment doesn't use.
+type DiscardNotifier struct{}
+
+func (DiscardNotifier) Send(ctx context.Context, message Message) error {
+	return nil
+}
+
+// FileNotifier appends every message to a file. Meant for local use.
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (n *FileNotifier) Send(ctx context.Context, message Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	file, err := os.OpenFile(n.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "Date: %s\nChannel: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), message.Channel, message.To, message.Subject, message.Body)
+	return err
+}
+
+// MemoryNotifier keeps every message in memory. Meant for tests.
+type MemoryNotifier struct {
+	messages []Message
+	mu       sync.Mutex
+}
+
+func (n *MemoryNotifier) Send(ctx context.Context, message Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+// Messages returns a copy of the messages sent so far.
+func (n *MemoryNotifier) Messages() []Message {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return append([]Message(nil), n.messages...)
+}
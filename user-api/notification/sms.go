@@ -0,0 +1,53 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// SMSWebhookNotifier posts SMS messages as JSON to an HTTP gateway.
+type SMSWebhookNotifier struct {
+	URL    string
+	Token  string // Sent as bearer token when not empty
+	Client *http.Client
+}
+
+type smsPayload struct {
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+func (n SMSWebhookNotifier) Send(ctx context.Context, message Message) error {
+	payload, err := json.Marshal(smsPayload{To: message.To, Body: message.Body})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: sendTimeout}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sms webhook answered %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package notification
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// sendTimeout bounds the delivery of a message when the context has no earlier
+// deadline, so an unreachable server can't hold the request that triggered it.
+const sendTimeout = 10 * time.Second
+
+// SMTPNotifier sends email messages through an SMTP server.
+type SMTPNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send does what smtp.SendMail does, upgrading to TLS when the server offers it,
+// but dials and talks to the server within the deadline of ctx.
+func (n SMTPNotifier) Send(ctx context.Context, message Message) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(n.Host, n.Port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, n.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.Host}); err != nil {
+			return err
+		}
+	}
+	if n.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.Username, n.Password, n.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(headerSanitizer.Replace(n.From)); err != nil {
+		return err
+	}
+	if err := client.Rcpt(headerSanitizer.Replace(message.To)); err != nil {
+		return err
+	}
+	data, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := data.Write(n.format(message)); err != nil {
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Header values never carry line breaks, so they can't inject extra headers
+var headerSanitizer = strings.NewReplacer("\r", "", "\n", "")
+
+func (n SMTPNotifier) format(message Message) []byte {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "From: %s\r\n", headerSanitizer.Replace(n.From))
+	fmt.Fprintf(&builder, "To: %s\r\n", headerSanitizer.Replace(message.To))
+	// Headers are ASCII, so a subject with accents goes as an RFC 2047 encoded word
+	fmt.Fprintf(&builder, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerSanitizer.Replace(message.Subject)))
+	fmt.Fprintf(&builder, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
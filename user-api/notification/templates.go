@@ -0,0 +1,92 @@
+package notification
+
+import (
+	"strings"
+	"text/template"
+)
+
+// TemplateData is the data available to every message template.
+type TemplateData struct {
+	Name     string
+	UserName string
+	Token    string
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	email   *template.Template
+	sms     *template.Template // nil when the message is never sent by SMS
+}
+
+func newTemplate(subject string, email string, sms string) messageTemplate {
+	t := messageTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		email:   template.Must(template.New("email").Parse(email)),
+	}
+	if sms != "" {
+		t.sms = template.Must(template.New("sms").Parse(sms))
+	}
+	return t
+}
+
+var templates = map[EventKind]messageTemplate{
+	EventUserRegistered: newTemplate(
+		"Bienvenido/a {{.Name}}",
+		`Hola {{.Name}},
+
+Tu cuenta {{.UserName}} fue creada con éxito.
+
+¡Gracias por registrarte!`,
+		"",
+	),
+	EventPasswordResetRequested: newTemplate(
+		"Restablecer contraseña",
+		`Hola {{.Name}},
+
+Recibimos un pedido para restablecer la contraseña de tu cuenta {{.UserName}}.
+Usa el siguiente código para elegir una nueva contraseña:
+
+{{.Token}}
+
+Si no fuiste vos, ignora este mensaje.`,
+		"Código para restablecer tu contraseña: {{.Token}}",
+	),
+	EventEmailVerificationRequested: newTemplate(
+		"Verifica tu email",
+		`Hola {{.Name}},
+
+Usa el siguiente código para verificar tu dirección de email:
+
+{{.Token}}`,
+		"",
+	),
+}
+
+// Render builds the messages of an event, one per channel the template supports.
+func Render(kind EventKind, data TemplateData) (subject string, email string, sms string, err error) {
+	t, ok := templates[kind]
+	if !ok {
+		return "", "", "", &UnknownEventError{Kind: kind}
+	}
+
+	if subject, err = execute(t.subject, data); err != nil {
+		return "", "", "", err
+	}
+	if email, err = execute(t.email, data); err != nil {
+		return "", "", "", err
+	}
+	if t.sms != nil {
+		if sms, err = execute(t.sms, data); err != nil {
+			return "", "", "", err
+		}
+	}
+	return subject, email, sms, nil
+}
+
+func execute(t *template.Template, data TemplateData) (string, error) {
+	var builder strings.Builder
+	if err := t.Execute(&builder, data); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,90 @@
+package search
+
+import (
+	"context"
+	"sync"
+	"user-api/model"
+)
+
+// MemoryIndex keeps every user in process and answers queries without touching the
+// database. It is filled with Load on the first call and kept current by Index and
+// Remove, so it only fits deployments with a single instance of the API.
+type MemoryIndex struct {
+	Load func(ctx context.Context) (model.Users, error)
+
+	mu        sync.RWMutex
+	loaded    bool
+	documents map[int]Document
+}
+
+func NewMemoryIndex(load func(ctx context.Context) (model.Users, error)) *MemoryIndex {
+	return &MemoryIndex{Load: load}
+}
+
+func (m *MemoryIndex) Search(ctx context.Context, query string, limit int) ([]Hit, error) {
+	if err := m.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	documents := make([]Document, 0, len(m.documents))
+	for _, document := range m.documents {
+		documents = append(documents, document)
+	}
+	m.mu.RUnlock()
+
+	return Rank(documents, Terms(query), limit), nil
+}
+
+func (m *MemoryIndex) Index(ctx context.Context, user model.User) error {
+	if err := m.ensureLoaded(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.documents[user.Id] = NewDocument(user)
+	return nil
+}
+
+func (m *MemoryIndex) Remove(ctx context.Context, id int) error {
+	if err := m.ensureLoaded(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.documents, id)
+	return nil
+}
+
+// ensureLoaded fills the index the first time it's used. A failed load is retried
+// on the next call.
+func (m *MemoryIndex) ensureLoaded(ctx context.Context) error {
+	m.mu.RLock()
+	loaded := m.loaded
+	m.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.loaded {
+		return nil
+	}
+
+	documents := map[int]Document{}
+	if m.Load != nil {
+		users, err := m.Load(ctx)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			documents[user.Id] = NewDocument(user)
+		}
+	}
+	m.documents = documents
+	m.loaded = true
+	return nil
+}
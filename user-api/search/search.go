@@ -0,0 +1,135 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+	"user-api/model"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxTerms caps how many words of a query are matched.
+const MaxTerms = 5
+
+// Backend finds users for a free-text query. Index and Remove keep the backend in
+// sync with the users table; backends that query the database directly ignore them.
+type Backend interface {
+	Search(ctx context.Context, query string, limit int) ([]Hit, error)
+	Index(ctx context.Context, user model.User) error
+	Remove(ctx context.Context, id int) error
+}
+
+// Hit is a user that matched a query, with its relevance score.
+type Hit struct {
+	User  model.User
+	Score int
+}
+
+// Field weights. A match on the name counts more than one on the email.
+var weights = [...]int{3, 3, 2, 1} // name, last name, username, email
+
+// Normalize lowercases s and strips its accents, so "José" and "jose" compare equal.
+func Normalize(s string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err != nil {
+		stripped = s
+	}
+	return strings.ToLower(stripped)
+}
+
+// Terms splits a query into its normalized, distinct words.
+func Terms(query string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, term := range strings.Fields(Normalize(query)) {
+		if seen[term] || len(terms) == MaxTerms {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// Document is a user with its searchable fields already normalized.
+type Document struct {
+	User   model.User
+	fields [len(weights)]string
+}
+
+func NewDocument(user model.User) Document {
+	return Document{
+		User:   user,
+		fields: [...]string{Normalize(user.Name), Normalize(user.LastName), Normalize(user.UserName), Normalize(user.Email)},
+	}
+}
+
+// Text is the content of the search_text column of the user.
+func (d Document) Text() string {
+	return strings.Join(d.fields[:], " ")
+}
+
+// Score returns how well the document matches every term. A term scores 3 when it
+// is a whole word of a field, 2 when it starts one and 1 when it appears anywhere,
+// times the weight of the best field. ok is false if any term doesn't match.
+func (d Document) Score(terms []string) (score int, ok bool) {
+	for _, term := range terms {
+		best := 0
+		for i, field := range d.fields {
+			if match := matchTerm(field, term) * weights[i]; match > best {
+				best = match
+			}
+		}
+		if best == 0 {
+			return 0, false
+		}
+		score += best
+	}
+	return score, len(terms) > 0
+}
+
+func matchTerm(field string, term string) int {
+	if !strings.Contains(field, term) {
+		return 0
+	}
+	match := 1
+	words := strings.FieldsFunc(field, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if word == term {
+			return 3
+		}
+		if strings.HasPrefix(word, term) {
+			match = 2
+		}
+	}
+	return match
+}
+
+// Rank scores the documents against the terms and returns the best limit hits,
+// highest score first and by id on ties.
+func Rank(documents []Document, terms []string, limit int) []Hit {
+	hits := []Hit{}
+	for _, document := range documents {
+		if score, ok := document.Score(terms); ok {
+			hits = append(hits, Hit{User: document.User, Score: score})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].User.Id < hits[j].User.Id
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"user-api/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "jose nunez", Normalize("José Núñez"))
+	assert.Equal(t, "muller", Normalize("MÜLLER"))
+	assert.Equal(t, "jperez@example.com", Normalize("JPerez@Example.com"))
+}
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"jose", "perez"}, Terms("  José   PÉREZ jose "))
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, Terms("a b c d e f"))
+	assert.Empty(t, Terms("   "))
+}
+
+func TestDocumentScore(t *testing.T) {
+	document := NewDocument(model.User{Name: "María José", LastName: "Pérez", UserName: "mjperez", Email: "mariajo@example.com"})
+
+	score, ok := document.Score([]string{"jose"})
+	assert.True(t, ok)
+	assert.Equal(t, 9, score) // Whole word of the name
+
+	score, ok = document.Score([]string{"per"})
+	assert.True(t, ok)
+	assert.Equal(t, 6, score) // Prefix of the last name
+
+	score, ok = document.Score([]string{"example"})
+	assert.True(t, ok)
+	assert.Equal(t, 3, score) // Whole word of the email
+
+	score, ok = document.Score([]string{"maria", "perez"})
+	assert.True(t, ok)
+	assert.Equal(t, 18, score)
+
+	// Every term has to match
+	_, ok = document.Score([]string{"maria", "gomez"})
+	assert.False(t, ok)
+
+	_, ok = document.Score([]string{})
+	assert.False(t, ok)
+}
+
+func TestRank(t *testing.T) {
+	documents := []Document{
+		NewDocument(model.User{Id: 1, Name: "Josefina", UserName: "jfina"}),
+		NewDocument(model.User{Id: 2, Name: "Ana", UserName: "ana"}),
+		NewDocument(model.User{Id: 3, Name: "José", UserName: "jose1"}),
+		NewDocument(model.User{Id: 4, Name: "Josefa", UserName: "jfa"}),
+	}
+
+	hits := Rank(documents, []string{"jose"}, 0)
+	assert.Len(t, hits, 3)
+	assert.Equal(t, 3, hits[0].User.Id)
+	assert.Equal(t, 1, hits[1].User.Id) // Same score, lower id first
+	assert.Equal(t, 4, hits[2].User.Id)
+
+	assert.Len(t, Rank(documents, []string{"jose"}, 2), 2)
+}
+
+func TestMemoryIndex(t *testing.T) {
+	loads := 0
+	index := NewMemoryIndex(func(ctx context.Context) (model.Users, error) {
+		loads++
+		if loads == 1 {
+			return nil, errors.New("db down")
+		}
+		return model.Users{{Id: 1, Name: "Ramón", LastName: "Díaz"}}, nil
+	})
+
+	// A failed load is retried
+	_, err := index.Search(context.Background(), "ramon", 10)
+	assert.Error(t, err)
+
+	hits, err := index.Search(context.Background(), "ramon", 10)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+
+	assert.NoError(t, index.Index(context.Background(), model.User{Id: 2, Name: "Ramona", LastName: "Díaz"}))
+	hits, _ = index.Search(context.Background(), "diaz", 10)
+	assert.Len(t, hits, 2)
+
+	// Index replaces the previous version of the user
+	assert.NoError(t, index.Index(context.Background(), model.User{Id: 1, Name: "Raúl", LastName: "Díaz"}))
+	hits, _ = index.Search(context.Background(), "raul", 10)
+	assert.Len(t, hits, 1)
+	hits, _ = index.Search(context.Background(), "diaz", 10)
+	assert.Len(t, hits, 2)
+
+	assert.NoError(t, index.Remove(context.Background(), 2))
+	hits, _ = index.Search(context.Background(), "diaz", 10)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, 2, loads)
+}
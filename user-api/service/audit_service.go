@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	userClient "user-api/client"
+	"user-api/dto"
+	"user-api/model"
+	e "user-api/utils/errors"
+	"user-api/utils/validation"
+)
+
+// AuditServiceInterface reads the audit log. Entries are written by the user
+// service as it changes users.
+type AuditServiceInterface interface {
+	GetAuditEntries(ctx context.Context, queryDto *dto.AuditQueryDto) (*dto.AuditPageDto, e.ApiError)
+}
+
+type auditService struct {
+	audit userClient.AuditClientInterface
+}
+
+func NewAuditService(audit userClient.AuditClientInterface) AuditServiceInterface {
+	return &auditService{audit: audit}
+}
+
+func (s *auditService) GetAuditEntries(ctx context.Context, queryDto *dto.AuditQueryDto) (*dto.AuditPageDto, e.ApiError) {
+	if apiErr := validation.Struct(queryDto); apiErr != nil {
+		return nil, apiErr
+	}
+
+	query := userClient.AuditQuery{
+		Limit:    queryDto.Limit,
+		Offset:   queryDto.Offset,
+		ActorId:  queryDto.ActorId,
+		TargetId: queryDto.TargetId,
+		Action:   queryDto.Action,
+		From:     queryDto.From,
+		To:       queryDto.To,
+	}
+	if query.Limit == 0 {
+		query.Limit = userClient.DefaultPageSize
+	}
+
+	page, err := s.audit.GetAuditEntries(ctx, query)
+	if err != nil {
+		return nil, clientApiError(err, e.MsgCouldNotGetAuditEntries)
+	}
+
+	entriesDto := []dto.AuditEntryDto{}
+	for _, entry := range page.Entries {
+		entriesDto = append(entriesDto, toAuditEntryDto(entry))
+	}
+
+	return &dto.AuditPageDto{
+		Items:  entriesDto,
+		Total:  page.Total,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}, nil
+}
+
+func toAuditEntryDto(entry model.AuditEntry) dto.AuditEntryDto {
+	changesDto := []dto.AuditChangeDto{}
+	for _, change := range entry.Changes {
+		changesDto = append(changesDto, dto.AuditChangeDto{Field: change.Field, Before: change.Before, After: change.After})
+	}
+
+	return dto.AuditEntryDto{
+		Id:        entry.Id,
+		ActorId:   entry.ActorId,
+		Action:    entry.Action,
+		TargetId:  entry.TargetId,
+		Changes:   changesDto,
+		RequestId: entry.RequestId,
+		CreatedAt: entry.CreatedAt,
+	}
+}
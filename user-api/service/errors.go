@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	userClient "user-api/client"
+	e "user-api/utils/errors"
+	"user-api/utils/i18n"
+	"user-api/utils/validation"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// uniqueFields are the unique columns of the users table, with the name the API
+// gives the field and the message reported when a value is already taken.
+var uniqueFields = map[string]struct {
+	field   string
+	message e.Message
+}{
+	"email":     {"email", e.MsgEmailTaken},
+	"user_name": {"username", e.MsgUserNameTaken},
+}
+
+// clientApiError maps an error of the client layer to an ApiError: a taken email
+// or username is a 409, an unavailable database a 503 and anything else a 500 with
+// the given message. Missing records are left to the callers, as what they mean
+// depends on the operation. The error itself is only logged: driver messages
+// name tables, columns and hosts and are not for the client.
+func clientApiError(err error, message e.Message) e.ApiError {
+	var duplicate *userClient.DuplicateKeyError
+	if errors.As(err, &duplicate) {
+		return duplicateApiError(duplicate.Column)
+	}
+
+	var transient *userClient.TransientError
+	if errors.As(err, &transient) {
+		log.Warn("Database unavailable: ", err)
+		return e.NewServiceUnavailableApiError(e.MsgServiceUnavailable, nil)
+	}
+
+	return internalApiError(message, err)
+}
+
+// internalApiError is the 500 for an unexpected error, which is logged and left
+// out of the response.
+func internalApiError(message e.Message, err error) e.ApiError {
+	log.Error(e.Translate(i18n.Default, message), ": ", err)
+	return e.NewInternalServerApiError(message, nil)
+}
+
+// duplicateApiError is the 409 for a value of a unique column that is already taken.
+func duplicateApiError(column string) e.ApiError {
+	unique, ok := uniqueFields[column]
+	if !ok {
+		unique.field, unique.message = column, e.MsgValueTaken
+	}
+
+	return e.NewDuplicateApiError(unique.message, e.CauseList{
+		validation.FieldError{Field: unique.field, Rule: "unique", Message: e.Translate(i18n.Default, unique.message)},
+	})
+}
+
+// writeApiError maps the error of a write to a user: a stale version is a 409, a
+// missing user a 404 and anything else goes through clientApiError.
+func writeApiError(err error, id int, message e.Message) e.ApiError {
+	if errors.Is(err, userClient.ErrStaleVersion) {
+		return conflictApiError(id)
+	}
+	if errors.Is(err, userClient.ErrNotFound) {
+		return e.NewNotFoundApiError(e.MsgUserNotFound)
+	}
+	return clientApiError(err, message)
+}
+
+// conflictApiError is the 409 for a write based on a version of the user that is
+// no longer the current one.
+func conflictApiError(id int) e.ApiError {
+	return e.NewConflictApiError("user " + strconv.Itoa(id))
+}
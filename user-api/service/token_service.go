@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	userClient "user-api/client"
+	"user-api/dto"
+	"user-api/model"
+	e "user-api/utils/errors"
+	"user-api/utils/token"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type tokenService struct {
+	users           userClient.UserClientInterface
+	refreshTokens   userClient.RefreshTokenClientInterface
+	signer          *token.Signer
+	refreshTokenTTL time.Duration
+}
+
+type TokenServiceInterface interface {
+	IssueTokens(ctx context.Context, user model.User) (*dto.TokenDto, e.ApiError)
+	RefreshTokens(ctx context.Context, refreshToken string) (*dto.TokenDto, e.ApiError)
+	Logout(ctx context.Context, refreshToken string) e.ApiError
+}
+
+// NewTokenService issues access tokens with the signer and refresh tokens that can be
+// exchanged for refreshTokenTTL.
+func NewTokenService(users userClient.UserClientInterface, refreshTokens userClient.RefreshTokenClientInterface, signer *token.Signer, refreshTokenTTL time.Duration) TokenServiceInterface {
+	return &tokenService{users: users, refreshTokens: refreshTokens, signer: signer, refreshTokenTTL: refreshTokenTTL}
+}
+
+// IssueTokens starts a new session (token family) for the user.
+func (s *tokenService) IssueTokens(ctx context.Context, user model.User) (*dto.TokenDto, e.ApiError) {
+	familyId, err := token.NewOpaque()
+	if err != nil {
+		return nil, internalApiError(e.MsgCouldNotGenerateToken, err)
+	}
+	return s.issue(ctx, user, familyId)
+}
+
+// RefreshTokens rotates a refresh token: the presented token is revoked and a new
+// pair is issued in the same family. Presenting an already revoked token is treated
+// as theft and revokes the whole family.
+func (s *tokenService) RefreshTokens(ctx context.Context, refreshToken string) (*dto.TokenDto, e.ApiError) {
+	stored, err := s.refreshTokens.GetRefreshTokenByHash(ctx, token.Hash(refreshToken))
+	if errors.Is(err, userClient.ErrNotFound) {
+		return nil, e.NewUnauthorizedApiError(e.MsgInvalidRefreshToken)
+	}
+	if err != nil {
+		return nil, clientApiError(err, e.MsgCouldNotRefreshToken)
+	}
+
+	if stored.RevokedAt != nil {
+		return nil, s.revokeFamily(ctx, stored)
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, e.NewUnauthorizedApiError(e.MsgExpiredRefreshToken)
+	}
+
+	revoked, err := s.refreshTokens.RevokeRefreshToken(ctx, stored.Id)
+	if err != nil {
+		return nil, clientApiError(err, e.MsgCouldNotRefreshToken)
+	}
+	if !revoked {
+		// Another request rotated this token first
+		return nil, s.revokeFamily(ctx, stored)
+	}
+
+	user, err := s.users.GetUserById(ctx, stored.UserId)
+	if errors.Is(err, userClient.ErrNotFound) {
+		return nil, e.NewUnauthorizedApiError(e.MsgInvalidRefreshToken)
+	}
+	if err != nil {
+		return nil, clientApiError(err, e.MsgCouldNotRefreshToken)
+	}
+
+	return s.issue(ctx, user, stored.FamilyId)
+}
+
+func (s *tokenService) Logout(ctx context.Context, refreshToken string) e.ApiError {
+	stored, err := s.refreshTokens.GetRefreshTokenByHash(ctx, token.Hash(refreshToken))
+	if errors.Is(err, userClient.ErrNotFound) {
+		return e.NewUnauthorizedApiError(e.MsgInvalidRefreshToken)
+	}
+	if err != nil {
+		return clientApiError(err, e.MsgCouldNotLogout)
+	}
+
+	if _, err := s.refreshTokens.RevokeRefreshToken(ctx, stored.Id); err != nil {
+		return clientApiError(err, e.MsgCouldNotLogout)
+	}
+	return nil
+}
+
+func (s *tokenService) revokeFamily(ctx context.Context, stored model.RefreshToken) e.ApiError {
+	log.Warn("Refresh token reuse detected, revoking family of user ", stored.UserId)
+	if err := s.refreshTokens.RevokeRefreshTokenFamily(ctx, stored.FamilyId); err != nil {
+		return clientApiError(err, e.MsgCouldNotRevokeSession)
+	}
+	return e.NewUnauthorizedApiError(e.MsgInvalidRefreshToken)
+}
+
+func (s *tokenService) issue(ctx context.Context, user model.User, familyId string) (*dto.TokenDto, e.ApiError) {
+	accessToken, expiresAt, err := s.signer.Generate(user.Id, user.Type)
+	if err != nil {
+		return nil, internalApiError(e.MsgCouldNotGenerateToken, err)
+	}
+
+	refreshToken, err := token.NewOpaque()
+	if err != nil {
+		return nil, internalApiError(e.MsgCouldNotGenerateToken, err)
+	}
+
+	refreshExpiresAt := time.Now().Add(s.refreshTokenTTL)
+	_, err = s.refreshTokens.InsertRefreshToken(ctx, model.RefreshToken{
+		UserId:    user.Id,
+		TokenHash: token.Hash(refreshToken),
+		FamilyId:  familyId,
+		ExpiresAt: refreshExpiresAt,
+	})
+	if err != nil {
+		return nil, clientApiError(err, e.MsgCouldNotGenerateToken)
+	}
+
+	return &dto.TokenDto{
+		AccessToken:      accessToken,
+		TokenType:        "Bearer",
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
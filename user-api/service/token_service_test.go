@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	userClient "user-api/client"
+	"user-api/model"
+	"user-api/utils/token"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testSigner signs the access tokens of the services under test
+var testSigner = token.NewSigner([]byte("test-secret"), 15*time.Minute)
+
+const testRefreshTokenTTL = 30 * 24 * time.Hour
+
+// Mock the refresh token client to simulate client responses
+type MockRefreshTokenClient struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenClient) InsertRefreshToken(ctx context.Context, refreshToken model.RefreshToken) (model.RefreshToken, error) {
+	args := m.Called(ctx, refreshToken)
+	return args.Get(0).(model.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenClient) GetRefreshTokenByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(model.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenClient) RevokeRefreshToken(ctx context.Context, id int) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRefreshTokenClient) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	args := m.Called(ctx, familyId)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenClient) RevokeUserRefreshTokens(ctx context.Context, userId int) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+
+func TestIssueTokens(t *testing.T) {
+
+	mockRefreshTokenClient := new(MockRefreshTokenClient)
+	tokenService := NewTokenService(new(MockUserClient), mockRefreshTokenClient, testSigner, testRefreshTokenTTL)
+
+	mockRefreshTokenClient.On("InsertRefreshToken", mock.Anything, mock.MatchedBy(func(refreshToken model.RefreshToken) bool {
+		return refreshToken.UserId == 1 && refreshToken.FamilyId != "" && len(refreshToken.TokenHash) == 64
+	})).Return(model.RefreshToken{Id: 1}, nil)
+
+	tokenDto, err := tokenService.IssueTokens(ctx, model.User{Id: 1})
+
+	assert.Nil(t, err)
+	assert.NotEmpty(t, tokenDto.AccessToken)
+	assert.NotEmpty(t, tokenDto.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), tokenDto.ExpiresAt, time.Minute)
+	assert.WithinDuration(t, time.Now().Add(testRefreshTokenTTL), tokenDto.RefreshExpiresAt, time.Minute)
+	mockRefreshTokenClient.AssertExpectations(t)
+}
+
+func TestRefreshTokens_Rotation(t *testing.T) {
+
+	mockUserClient := new(MockUserClient)
+	mockRefreshTokenClient := new(MockRefreshTokenClient)
+	tokenService := NewTokenService(mockUserClient, mockRefreshTokenClient, testSigner, testRefreshTokenTTL)
+
+	stored := model.RefreshToken{Id: 3, UserId: 1, FamilyId: "family", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mockRefreshTokenClient.On("GetRefreshTokenByHash", mock.Anything, token.Hash("old-token")).Return(stored, nil)
+	mockRefreshTokenClient.On("RevokeRefreshToken", mock.Anything, 3).Return(true, nil)
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(model.User{Id: 1}, nil)
+	mockRefreshTokenClient.On("InsertRefreshToken", mock.Anything, mock.MatchedBy(func(refreshToken model.RefreshToken) bool {
+		return refreshToken.FamilyId == "family"
+	})).Return(model.RefreshToken{Id: 4}, nil)
+
+	tokenDto, err := tokenService.RefreshTokens(ctx, "old-token")
+
+	assert.Nil(t, err)
+	assert.NotEqual(t, "old-token", tokenDto.RefreshToken)
+	mockUserClient.AssertExpectations(t)
+	mockRefreshTokenClient.AssertExpectations(t)
+}
+
+func TestRefreshTokens_ReuseRevokesFamily(t *testing.T) {
+
+	mockRefreshTokenClient := new(MockRefreshTokenClient)
+	tokenService := NewTokenService(new(MockUserClient), mockRefreshTokenClient, testSigner, testRefreshTokenTTL)
+
+	revokedAt := time.Now().Add(-time.Minute)
+	stored := model.RefreshToken{Id: 3, UserId: 1, FamilyId: "family", ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}
+
+	mockRefreshTokenClient.On("GetRefreshTokenByHash", mock.Anything, token.Hash("stolen-token")).Return(stored, nil)
+	mockRefreshTokenClient.On("RevokeRefreshTokenFamily", mock.Anything, "family").Return(nil)
+
+	tokenDto, err := tokenService.RefreshTokens(ctx, "stolen-token")
+
+	assert.Nil(t, tokenDto)
+	assert.Equal(t, 401, err.Status())
+	mockRefreshTokenClient.AssertExpectations(t)
+}
+
+func TestRefreshTokens_ConcurrentRotationRevokesFamily(t *testing.T) {
+
+	mockRefreshTokenClient := new(MockRefreshTokenClient)
+	tokenService := NewTokenService(new(MockUserClient), mockRefreshTokenClient, testSigner, testRefreshTokenTTL)
+
+	stored := model.RefreshToken{Id: 3, UserId: 1, FamilyId: "family", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mockRefreshTokenClient.On("GetRefreshTokenByHash", mock.Anything, token.Hash("raced-token")).Return(stored, nil)
+	mockRefreshTokenClient.On("RevokeRefreshToken", mock.Anything, 3).Return(false, nil)
+	mockRefreshTokenClient.On("RevokeRefreshTokenFamily", mock.Anything, "family").Return(nil)
+
+	tokenDto, err := tokenService.RefreshTokens(ctx, "raced-token")
+
+	assert.Nil(t, tokenDto)
+	assert.Equal(t, 401, err.Status())
+	mockRefreshTokenClient.AssertExpectations(t)
+}
+
+func TestRefreshTokens_Expired(t *testing.T) {
+
+	mockRefreshTokenClient := new(MockRefreshTokenClient)
+	tokenService := NewTokenService(new(MockUserClient), mockRefreshTokenClient, testSigner, testRefreshTokenTTL)
+
+	stored := model.RefreshToken{Id: 3, UserId: 1, FamilyId: "family", ExpiresAt: time.Now().Add(-time.Hour)}
+
+	mockRefreshTokenClient.On("GetRefreshTokenByHash", mock.Anything, token.Hash("expired-token")).Return(stored, nil)
+
+	tokenDto, err := tokenService.RefreshTokens(ctx, "expired-token")
+
+	assert.Nil(t, tokenDto)
+	assert.Equal(t, "Refresh token expirado", err.Message())
+	mockRefreshTokenClient.AssertExpectations(t)
+}
+
+func TestRefreshTokens_Unknown(t *testing.T) {
+
+	mockRefreshTokenClient := new(MockRefreshTokenClient)
+	tokenService := NewTokenService(new(MockUserClient), mockRefreshTokenClient, testSigner, testRefreshTokenTTL)
+
+	mockRefreshTokenClient.On("GetRefreshTokenByHash", mock.Anything, token.Hash("unknown")).Return(model.RefreshToken{}, userClient.ErrNotFound)
+
+	tokenDto, err := tokenService.RefreshTokens(ctx, "unknown")
+
+	assert.Nil(t, tokenDto)
+	assert.Equal(t, 401, err.Status())
+	mockRefreshTokenClient.AssertExpectations(t)
+}
+
+func TestLogout(t *testing.T) {
+
+	mockRefreshTokenClient := new(MockRefreshTokenClient)
+	tokenService := NewTokenService(new(MockUserClient), mockRefreshTokenClient, testSigner, testRefreshTokenTTL)
+
+	stored := model.RefreshToken{Id: 3, UserId: 1, FamilyId: "family"}
+
+	mockRefreshTokenClient.On("GetRefreshTokenByHash", mock.Anything, token.Hash("current-token")).Return(stored, nil)
+	mockRefreshTokenClient.On("RevokeRefreshToken", mock.Anything, 3).Return(true, nil)
+
+	err := tokenService.Logout(ctx, "current-token")
+
+	assert.Nil(t, err)
+	mockRefreshTokenClient.AssertExpectations(t)
+}
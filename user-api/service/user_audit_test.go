@@ -0,0 +1,196 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	"user-api/audit"
+	userClient "user-api/client"
+	"user-api/config"
+	"user-api/db"
+	"user-api/dto"
+	"user-api/model"
+	"user-api/notification"
+	"user-api/search"
+	"user-api/utils/requestid"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// auditEntries returns the audit entries the mock user client recorded.
+func auditEntries(mocks userServiceMocks) []model.AuditEntry {
+	return mocks.users.audited
+}
+
+func TestInsertUser_RecordsAudit(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("EmailTaken", mock.Anything, "jdoe@example.com").Return(false, nil)
+	mockUserClient.On("InsertUser", mock.Anything, mock.Anything, mock.Anything).Return(model.User{Id: 1, Name: "John", UserName: "jdoe", Email: "jdoe@example.com", Password: "$2a$hash"}, nil)
+	mocks.oneTimeTokens.On("InsertOneTimeToken", mock.Anything, mock.Anything).Return(model.OneTimeToken{Id: 1}, nil)
+
+	_, err := userService.InsertUser(requestid.NewContext(ctx, "req-1"), &dto.UserCreateDto{
+		Name:     "John",
+		LastName: "Doe",
+		UserName: "jdoe",
+		Email:    "jdoe@example.com",
+		Password: "password123",
+	})
+	require.Nil(t, err)
+
+	entries := auditEntries(mocks)
+	require.Len(t, entries, 1)
+	assert.Equal(t, model.AuditActionCreate, entries[0].Action)
+	assert.Equal(t, 1, entries[0].TargetId)
+	assert.Nil(t, entries[0].ActorId) // Signing up needs no login
+	assert.Equal(t, "req-1", entries[0].RequestId)
+
+	changes, _ := json.Marshal(entries[0].Changes)
+	assert.Contains(t, string(changes), `"jdoe@example.com"`)
+	assert.NotContains(t, string(changes), "$2a$")
+}
+
+func TestUpdateUser_RecordsAudit(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUser := model.User{Id: 1, Name: "John", LastName: "Doe", UserName: "jdoe", Email: "jdoe@example.com", Version: 1}
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(mockUser, nil)
+	mockUserClient.On("UpdateUser", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	actorCtx := audit.NewContext(requestid.NewContext(ctx, "req-2"), 9)
+	_, err := userService.UpdateUser(actorCtx, 1, 1, &dto.UserUpdateDto{Name: "Johnny", LastName: "Doe", UserName: "jdoe", Email: "jdoe@example.com"})
+	require.Nil(t, err)
+
+	entries := auditEntries(mocks)
+	require.Len(t, entries, 1)
+	assert.Equal(t, model.AuditActionUpdate, entries[0].Action)
+	assert.Equal(t, 1, entries[0].TargetId)
+	require.NotNil(t, entries[0].ActorId)
+	assert.Equal(t, 9, *entries[0].ActorId)
+	assert.Equal(t, "req-2", entries[0].RequestId)
+	assert.Equal(t, []model.AuditChange{
+		{Field: "name", Before: json.RawMessage(`"John"`), After: json.RawMessage(`"Johnny"`)},
+	}, entries[0].Changes)
+
+	// An update that changes nothing is not recorded
+	_, err = userService.UpdateUser(actorCtx, 1, 1, &dto.UserUpdateDto{Name: "John", LastName: "Doe", UserName: "jdoe", Email: "jdoe@example.com"})
+	require.Nil(t, err)
+	assert.Len(t, auditEntries(mocks), 1)
+}
+
+func TestDeleteUser_RecordsAudit(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(model.User{Id: 1, UserName: "jdoe", Password: "$2a$hash", Version: 1}, nil)
+	mockUserClient.On("DeleteUser", mock.Anything, 1, 1, mock.Anything).Return(nil)
+	mocks.refreshTokens.On("RevokeUserRefreshTokens", mock.Anything, 1).Return(nil)
+
+	err := userService.DeleteUser(audit.NewContext(ctx, 9), 1, 1)
+	require.Nil(t, err)
+
+	entries := auditEntries(mocks)
+	require.Len(t, entries, 1)
+	assert.Equal(t, model.AuditActionDelete, entries[0].Action)
+	require.NotNil(t, entries[0].ActorId)
+	assert.Equal(t, 9, *entries[0].ActorId)
+	for _, change := range entries[0].Changes {
+		assert.Nil(t, change.After)
+		assert.NotContains(t, string(change.Before), "$2a$")
+	}
+}
+
+func TestChangePassword_RecordsRedactedAudit(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	hashedPassword, _ := userService.HashPassword("password123")
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(model.User{Id: 1, UserName: "jdoe", Password: hashedPassword}, nil)
+	mockUserClient.On("UpdateUser", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mocks.refreshTokens.On("RevokeUserRefreshTokens", mock.Anything, 1).Return(nil)
+
+	err := userService.ChangePassword(ctx, 1, &dto.PasswordChangeDto{CurrentPassword: "password123", NewPassword: "newpassword456"})
+	require.Nil(t, err)
+
+	entries := auditEntries(mocks)
+	require.Len(t, entries, 1)
+	assert.Equal(t, []model.AuditChange{
+		{Field: "password", Before: json.RawMessage(`"[REDACTED]"`), After: json.RawMessage(`"[REDACTED]"`)},
+	}, entries[0].Changes)
+}
+
+func TestGetAuditEntries(t *testing.T) {
+
+	auditClient := userClient.NewMemoryAuditClient(userClient.NewMemoryStore())
+	actorId := 9
+	now := time.Now()
+	for _, entry := range []model.AuditEntry{
+		{Action: model.AuditActionCreate, TargetId: 1, CreatedAt: now.Add(-2 * time.Hour)},
+		{ActorId: &actorId, Action: model.AuditActionUpdate, TargetId: 1, CreatedAt: now.Add(-time.Hour), Changes: []model.AuditChange{{Field: "name", Before: json.RawMessage(`"John"`), After: json.RawMessage(`"Johnny"`)}}},
+		{ActorId: &actorId, Action: model.AuditActionDelete, TargetId: 2, CreatedAt: now},
+	} {
+		_, err := auditClient.InsertAuditEntry(ctx, entry)
+		require.NoError(t, err)
+	}
+	auditService := NewAuditService(auditClient)
+
+	pageDto, err := auditService.GetAuditEntries(ctx, &dto.AuditQueryDto{})
+	require.Nil(t, err)
+	assert.Equal(t, 3, pageDto.Total)
+	assert.Equal(t, userClient.DefaultPageSize, pageDto.Limit)
+	assert.Equal(t, model.AuditActionDelete, pageDto.Items[0].Action) // Newest first
+
+	pageDto, err = auditService.GetAuditEntries(ctx, &dto.AuditQueryDto{ActorId: 9, TargetId: 1})
+	require.Nil(t, err)
+	require.Len(t, pageDto.Items, 1)
+	assert.Equal(t, model.AuditActionUpdate, pageDto.Items[0].Action)
+	assert.Equal(t, "name", pageDto.Items[0].Changes[0].Field)
+
+	pageDto, err = auditService.GetAuditEntries(ctx, &dto.AuditQueryDto{From: now.Add(-90 * time.Minute), To: now})
+	require.Nil(t, err)
+	require.Len(t, pageDto.Items, 1)
+	assert.Equal(t, model.AuditActionUpdate, pageDto.Items[0].Action)
+
+	// Test case: unknown action
+	_, err = auditService.GetAuditEntries(ctx, &dto.AuditQueryDto{Action: "rename"})
+	require.NotNil(t, err)
+	assert.Equal(t, 400, err.Status())
+}
+
+func TestChangePassword_AuditFailure(t *testing.T) {
+
+	database, err := db.Open(config.DatabaseConfig{Driver: config.DriverSQLite, Name: ":memory:"})
+	require.NoError(t, err)
+	defer db.Close(database)
+	require.NoError(t, db.StartDbEngine(database))
+
+	users := userClient.NewUserClient(database)
+	refreshTokens := new(MockRefreshTokenClient)
+	userService := NewUserService(users, refreshTokens, new(MockOneTimeTokenClient), nil, notification.Dispatcher{Notifier: &notification.MemoryNotifier{}}, search.NewMemoryIndex(nil), DefaultUserSettings()).(*userService)
+
+	hashedPassword, _ := userService.HashPassword("password123")
+	user, err := users.InsertUser(ctx, model.User{UserName: "jdoe", Email: "jdoe@example.com", Password: hashedPassword}, model.AuditEntry{})
+	require.NoError(t, err)
+
+	// Without the audit log the change can't be recorded
+	require.NoError(t, database.Migrator().DropTable(&model.AuditEntry{}))
+
+	apiErr := userService.ChangePassword(ctx, user.Id, &dto.PasswordChangeDto{CurrentPassword: "password123", NewPassword: "newpassword456"})
+	require.NotNil(t, apiErr)
+	assert.Equal(t, 500, apiErr.Status())
+
+	// The password is rolled back together with the audit entry
+	stored, err := users.GetUserById(ctx, user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, hashedPassword, stored.Password)
+	assert.Equal(t, 1, stored.Version)
+	refreshTokens.AssertNotCalled(t, "RevokeUserRefreshTokens", mock.Anything, mock.Anything)
+}
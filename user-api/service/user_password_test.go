@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	userClient "user-api/client"
+	"user-api/dto"
+	"user-api/model"
+	"user-api/utils/token"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// Mock the one-time token client to simulate client responses
+type MockOneTimeTokenClient struct {
+	mock.Mock
+}
+
+func (m *MockOneTimeTokenClient) InsertOneTimeToken(ctx context.Context, oneTimeToken model.OneTimeToken) (model.OneTimeToken, error) {
+	args := m.Called(ctx, oneTimeToken)
+	return args.Get(0).(model.OneTimeToken), args.Error(1)
+}
+
+func (m *MockOneTimeTokenClient) GetOneTimeTokenByHash(ctx context.Context, hash string, purpose string) (model.OneTimeToken, error) {
+	args := m.Called(ctx, hash, purpose)
+	return args.Get(0).(model.OneTimeToken), args.Error(1)
+}
+
+func (m *MockOneTimeTokenClient) UseOneTimeToken(ctx context.Context, id int) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOneTimeTokenClient) RevokeUserOneTimeTokens(ctx context.Context, userId int, purpose string) error {
+	args := m.Called(ctx, userId, purpose)
+	return args.Error(0)
+}
+
+func TestChangePassword_Success(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+	mockRefreshTokenClient := mocks.refreshTokens
+
+	hashedPassword, _ := userService.HashPassword("password123")
+	mockUser := model.User{Id: 1, UserName: "jdoe", Password: hashedPassword}
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(mockUser, nil)
+	mockUserClient.On("UpdateUser", mock.Anything, mock.MatchedBy(func(user model.User) bool {
+		return userService.VerifyPassword(user.Password, "newpassword456") == nil
+	}), mock.Anything).Return(nil)
+	mockRefreshTokenClient.On("RevokeUserRefreshTokens", mock.Anything, 1).Return(nil)
+
+	err := userService.ChangePassword(ctx, 1, &dto.PasswordChangeDto{CurrentPassword: "password123", NewPassword: "newpassword456"})
+
+	assert.Nil(t, err)
+	mockUserClient.AssertExpectations(t)
+	mockRefreshTokenClient.AssertExpectations(t)
+}
+
+func TestChangePassword_WrongCurrentPassword(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	hashedPassword, _ := userService.HashPassword("password123")
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(model.User{Id: 1, Password: hashedPassword}, nil)
+
+	err := userService.ChangePassword(ctx, 1, &dto.PasswordChangeDto{CurrentPassword: "wrong", NewPassword: "newpassword456"})
+
+	assert.Equal(t, "La contraseña actual es incorrecta", err.Message())
+	mockUserClient.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestForgotPassword_SendsToken(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+	mockOneTimeTokenClient := mocks.oneTimeTokens
+	notifier := mocks.notifier
+
+	var storedHash string
+	mockUserClient.On("FindUserByEmail", mock.Anything, "jdoe@example.com").Return(model.User{Id: 1, Email: "jdoe@example.com"}, nil)
+	mockOneTimeTokenClient.On("InsertOneTimeToken", mock.Anything, mock.MatchedBy(func(oneTimeToken model.OneTimeToken) bool {
+		storedHash = oneTimeToken.TokenHash
+		return oneTimeToken.UserId == 1 && oneTimeToken.Purpose == model.PurposePasswordReset
+	})).Return(model.OneTimeToken{Id: 1}, nil)
+
+	requestCtx, cancel := context.WithCancel(ctx)
+	err := userService.ForgotPassword(requestCtx, &dto.PasswordForgotDto{Email: "jdoe@example.com"})
+	cancel()
+
+	// The token is issued and sent after the response, whatever happens to the request
+	assert.Nil(t, err)
+	require.Eventually(t, func() bool { return len(notifier.Messages()) == 1 }, time.Second, 10*time.Millisecond)
+	messages := notifier.Messages()
+	assert.Equal(t, "jdoe@example.com", messages[0].To)
+
+	// The message carries the raw token, only its hash is persisted
+	found := false
+	for _, word := range strings.Fields(messages[0].Body) {
+		found = found || token.Hash(word) == storedHash
+	}
+	assert.True(t, found)
+	mockUserClient.AssertExpectations(t)
+	mockOneTimeTokenClient.AssertExpectations(t)
+}
+
+func TestForgotPassword_UnknownEmail(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+	notifier := mocks.notifier
+
+	mockUserClient.On("FindUserByEmail", mock.Anything, "ghost@example.com").Return(model.User{}, userClient.ErrNotFound)
+
+	err := userService.ForgotPassword(ctx, &dto.PasswordForgotDto{Email: "ghost@example.com"})
+
+	assert.Nil(t, err)
+	assert.Empty(t, notifier.Messages())
+}
+
+func TestResetPassword_Success(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+	mockOneTimeTokenClient := mocks.oneTimeTokens
+	mockRefreshTokenClient := mocks.refreshTokens
+
+	stored := model.OneTimeToken{Id: 5, UserId: 1, Purpose: model.PurposePasswordReset, ExpiresAt: time.Now().Add(time.Hour)}
+
+	mockOneTimeTokenClient.On("GetOneTimeTokenByHash", mock.Anything, token.Hash("reset-token"), model.PurposePasswordReset).Return(stored, nil)
+	mockOneTimeTokenClient.On("UseOneTimeToken", mock.Anything, 5).Return(true, nil)
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(model.User{Id: 1}, nil)
+	mockUserClient.On("UpdateUser", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRefreshTokenClient.On("RevokeUserRefreshTokens", mock.Anything, 1).Return(nil)
+
+	err := userService.ResetPassword(ctx, &dto.PasswordResetDto{Token: "reset-token", NewPassword: "newpassword456"})
+
+	assert.Nil(t, err)
+	mockUserClient.AssertExpectations(t)
+	mockOneTimeTokenClient.AssertExpectations(t)
+	mockRefreshTokenClient.AssertExpectations(t)
+}
+
+func TestResetPassword_AlreadyUsed(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockOneTimeTokenClient := mocks.oneTimeTokens
+
+	usedAt := time.Now()
+	stored := model.OneTimeToken{Id: 5, UserId: 1, ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt}
+	mockOneTimeTokenClient.On("GetOneTimeTokenByHash", mock.Anything, token.Hash("reset-token"), model.PurposePasswordReset).Return(stored, nil)
+
+	err := userService.ResetPassword(ctx, &dto.PasswordResetDto{Token: "reset-token", NewPassword: "newpassword456"})
+
+	assert.Equal(t, "Token de restablecimiento inválido o expirado", err.Message())
+	mockOneTimeTokenClient.AssertNotCalled(t, "UseOneTimeToken", mock.Anything, mock.Anything)
+}
+
+func TestResetPassword_Expired(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockOneTimeTokenClient := mocks.oneTimeTokens
+
+	stored := model.OneTimeToken{Id: 5, UserId: 1, ExpiresAt: time.Now().Add(-time.Minute)}
+	mockOneTimeTokenClient.On("GetOneTimeTokenByHash", mock.Anything, token.Hash("reset-token"), model.PurposePasswordReset).Return(stored, nil)
+
+	err := userService.ResetPassword(ctx, &dto.PasswordResetDto{Token: "reset-token", NewPassword: "newpassword456"})
+
+	assert.Equal(t, 400, err.Status())
+	mockOneTimeTokenClient.AssertNotCalled(t, "UseOneTimeToken", mock.Anything, mock.Anything)
+}
+
+func TestChangePassword_WeakPassword(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	err := userService.ChangePassword(ctx, 1, &dto.PasswordChangeDto{CurrentPassword: "password123", NewPassword: "short"})
+
+	assert.Equal(t, "validation_error", err.Code())
+	assert.Len(t, err.Cause(), 1)
+	mockUserClient.AssertNotCalled(t, "GetUserById", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"testing"
+	userClient "user-api/client"
+	"user-api/dto"
+	"user-api/model"
+	e "user-api/utils/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func patchTestUser() model.User {
+	return model.User{
+		Id:       1,
+		Name:     "John",
+		LastName: "Doe",
+		UserName: "jdoe",
+		Email:    "jdoe@example.com",
+		Phone:    1155554444,
+		Address:  "Calle 123",
+		Password: "$2a$10$hash",
+		Version:  1,
+	}
+}
+
+func TestPatchUser_MergePatchOnlyChangesSuppliedFields(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(patchTestUser(), nil)
+	mockUserClient.On("UpdateUser", mock.Anything, mock.MatchedBy(func(user model.User) bool {
+		return user.Name == "Johnny" &&
+			user.LastName == "Doe" &&
+			user.Email == "jdoe@example.com" &&
+			user.Phone == 1155554444 &&
+			user.Address == "" &&
+			user.Password == "$2a$10$hash"
+	}), mock.Anything).Return(nil)
+
+	updatedUser, err := userService.PatchUser(ctx, 1, 1, []byte(`{"name":"Johnny","address":null}`), MergePatch)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Johnny", updatedUser.Name)
+	assert.Equal(t, "jdoe", updatedUser.UserName)
+	// Neither email nor username changed, so uniqueness is not checked again
+	mockUserClient.AssertNotCalled(t, "EmailTaken", mock.Anything, mock.Anything)
+	mockUserClient.AssertNotCalled(t, "GetUserByUsername", mock.Anything, mock.Anything)
+	mockUserClient.AssertExpectations(t)
+}
+
+func TestPatchUser_JSONPatch(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(patchTestUser(), nil)
+	mockUserClient.On("GetUserByUsername", mock.Anything, "johnny").Return(model.User{}, userClient.ErrNotFound)
+	mockUserClient.On("UpdateUser", mock.Anything, mock.MatchedBy(func(user model.User) bool {
+		return user.UserName == "johnny" && user.Name == "John"
+	}), mock.Anything).Return(nil)
+
+	patch := `[{"op":"test","path":"/username","value":"jdoe"},{"op":"replace","path":"/username","value":"johnny"}]`
+	updatedUser, err := userService.PatchUser(ctx, 1, 1, []byte(patch), JSONPatch)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "johnny", updatedUser.UserName)
+	mockUserClient.AssertExpectations(t)
+}
+
+func TestPatchUser_FailedJSONPatchTest(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(patchTestUser(), nil)
+
+	patch := `[{"op":"test","path":"/username","value":"someone"},{"op":"replace","path":"/username","value":"johnny"}]`
+	updatedUser, err := userService.PatchUser(ctx, 1, 1, []byte(patch), JSONPatch)
+
+	assert.Nil(t, updatedUser)
+	assert.Equal(t, 400, err.Status())
+	mockUserClient.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestPatchUser_RemovingRequiredField(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(patchTestUser(), nil)
+
+	updatedUser, err := userService.PatchUser(ctx, 1, 1, []byte(`{"email":null}`), MergePatch)
+
+	assert.Nil(t, updatedUser)
+	assert.Equal(t, 400, err.Status())
+	mockUserClient.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestPatchUser_CannotPatchPassword(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(patchTestUser(), nil)
+
+	updatedUser, err := userService.PatchUser(ctx, 1, 1, []byte(`{"password":"hacked"}`), MergePatch)
+
+	assert.Nil(t, updatedUser)
+	assert.Equal(t, 400, err.Status())
+	mockUserClient.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestPatchUser_EmailTaken(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(patchTestUser(), nil)
+	mockUserClient.On("EmailTaken", mock.Anything, "taken@example.com").Return(true, nil)
+
+	updatedUser, err := userService.PatchUser(ctx, 1, 1, []byte(`{"email":"taken@example.com"}`), MergePatch)
+
+	assert.Nil(t, updatedUser)
+	assert.Equal(t, 409, err.Status())
+	assert.Equal(t, "El email ya está registrado", err.Message())
+	mockUserClient.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestPatchUser_UnsupportedType(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(patchTestUser(), nil)
+
+	updatedUser, err := userService.PatchUser(ctx, 1, 1, []byte(`name=John`), "application/x-www-form-urlencoded")
+
+	assert.Nil(t, updatedUser)
+	assert.Equal(t, 415, err.Status())
+}
+
+func TestPatchUser_NotFound(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 2).Return(model.User{}, userClient.ErrNotFound)
+
+	updatedUser, err := userService.PatchUser(ctx, 2, 1, []byte(`{"name":"John"}`), MergePatch)
+
+	assert.Nil(t, updatedUser)
+	assert.Equal(t, 404, err.Status())
+	assert.Equal(t, "Usuario no encontrado", err.Message())
+}
+
+func TestUpdateUser_UsernameTaken(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(patchTestUser(), nil)
+	mockUserClient.On("GetUserByUsername", mock.Anything, "taken").Return(model.User{Id: 2, UserName: "taken"}, nil)
+
+	updatedUser, err := userService.UpdateUser(ctx, 1, 1, &dto.UserUpdateDto{Name: "John", LastName: "Doe", UserName: "taken", Email: "jdoe@example.com"})
+
+	assert.Nil(t, updatedUser)
+	assert.Equal(t, 409, err.Status())
+	assert.Equal(t, "Nombre de usuario repetido", err.Message())
+	mockUserClient.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestUpdateUser_StaleVersion(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	// The changes are based on version 1 but the user is already at version 2
+	stored := patchTestUser()
+	stored.Version = 2
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(stored, nil)
+
+	updatedUser, err := userService.UpdateUser(ctx, 1, 1, &dto.UserUpdateDto{Name: "John", LastName: "Doe", UserName: "jdoe", Email: "jdoe@example.com"})
+
+	assert.Nil(t, updatedUser)
+	assert.Equal(t, 409, err.Status())
+	assert.Equal(t, "conflict_error", err.Code())
+	mockUserClient.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestPatchUser_ConcurrentUpdate(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	// Someone else saves the user between the read and the write
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(patchTestUser(), nil)
+	mockUserClient.On("UpdateUser", mock.Anything, mock.Anything, mock.Anything).Return(userClient.ErrStaleVersion)
+
+	updatedUser, err := userService.PatchUser(ctx, 1, 1, []byte(`{"name":"Johnny"}`), MergePatch)
+
+	assert.Nil(t, updatedUser)
+	assert.Equal(t, 409, err.Status())
+}
+
+func TestUpdateUser_ReturnsNewVersion(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(patchTestUser(), nil)
+	mockUserClient.On("UpdateUser", mock.Anything, mock.MatchedBy(func(user model.User) bool {
+		return user.Version == 1
+	}), mock.Anything).Return(nil)
+
+	updatedUser, err := userService.PatchUser(ctx, 1, 1, []byte(`{"name":"Johnny"}`), MergePatch)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, updatedUser.Version)
+}
+
+func TestDeleteUser_StaleVersion(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(patchTestUser(), nil)
+	mockUserClient.On("DeleteUser", mock.Anything, 1, 1, mock.Anything).Return(userClient.ErrStaleVersion)
+
+	err := userService.DeleteUser(ctx, 1, 1)
+
+	assert.Equal(t, 409, err.(e.ApiError).Status())
+	mocks.refreshTokens.AssertNotCalled(t, "RevokeUserRefreshTokens", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"user-api/dto"
+	"user-api/model"
+	"user-api/search"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSearchUsers(t *testing.T) {
+
+	userService, _ := newTestUserService()
+	userService.userSearch = search.NewMemoryIndex(func(ctx context.Context) (model.Users, error) {
+		return model.Users{
+			{Id: 1, Name: "José", LastName: "Pérez", UserName: "jperez", Email: "jose@example.com", Password: "hash"},
+			{Id: 2, Name: "Josefina", LastName: "Gómez", UserName: "jgomez", Email: "josefina@example.com"},
+			{Id: 3, Name: "Ana", LastName: "Núñez", UserName: "anunez", Email: "ana@example.com"},
+		}, nil
+	})
+
+	resultDto, err := userService.SearchUsers(ctx, &dto.UserSearchQueryDto{Q: "jose"})
+
+	assert.Nil(t, err)
+	assert.Len(t, resultDto.Items, 2)
+	assert.Equal(t, 1, resultDto.Items[0].Id) // Whole word beats prefix
+	assert.Equal(t, 2, resultDto.Items[1].Id)
+	assert.Greater(t, resultDto.Items[0].Score, resultDto.Items[1].Score)
+
+	// Test case: limit
+	resultDto, err = userService.SearchUsers(ctx, &dto.UserSearchQueryDto{Q: "jose", Limit: 1})
+	assert.Nil(t, err)
+	assert.Len(t, resultDto.Items, 1)
+
+	// Test case: no match
+	resultDto, err = userService.SearchUsers(ctx, &dto.UserSearchQueryDto{Q: "nadie"})
+	assert.Nil(t, err)
+	assert.Empty(t, resultDto.Items)
+}
+
+func TestSearchUsers_InvalidQuery(t *testing.T) {
+
+	userService, _ := newTestUserService()
+
+	resultDto, err := userService.SearchUsers(ctx, &dto.UserSearchQueryDto{Limit: 500})
+
+	assert.Nil(t, resultDto)
+	assert.Equal(t, "validation_error", err.Code())
+	assert.Len(t, err.Cause(), 2)
+}
+
+func TestSearchUsers_KeepsIndexInSync(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+	mockOneTimeTokenClient := mocks.oneTimeTokens
+	userService.userSearch = search.NewMemoryIndex(nil)
+
+	mockUserClient.On("EmailTaken", mock.Anything, "nunez@example.com").Return(false, nil)
+	mockUserClient.On("InsertUser", mock.Anything, mock.Anything, mock.Anything).Return(model.User{Id: 7, Name: "Ana", LastName: "Núñez", UserName: "anunez", Email: "nunez@example.com"}, nil)
+	mockOneTimeTokenClient.On("InsertOneTimeToken", mock.Anything, mock.Anything).Return(model.OneTimeToken{Id: 1}, nil)
+	mockUserClient.On("GetUserById", mock.Anything, 7).Return(model.User{Id: 7, Version: 1}, nil)
+	mockUserClient.On("DeleteUser", mock.Anything, 7, 1, mock.Anything).Return(nil)
+	mockRefreshTokenClient := mocks.refreshTokens
+	mockRefreshTokenClient.On("RevokeUserRefreshTokens", mock.Anything, 7).Return(nil)
+
+	_, apiErr := userService.InsertUser(ctx, &dto.UserCreateDto{
+		Name: "Ana", LastName: "Núñez", UserName: "anunez", Password: "secreto123", Email: "nunez@example.com",
+	})
+	assert.Nil(t, apiErr)
+
+	resultDto, _ := userService.SearchUsers(ctx, &dto.UserSearchQueryDto{Q: "NUNEZ"})
+	assert.Len(t, resultDto.Items, 1)
+
+	assert.Nil(t, userService.DeleteUser(ctx, 7, 1))
+
+	resultDto, _ = userService.SearchUsers(ctx, &dto.UserSearchQueryDto{Q: "nunez"})
+	assert.Empty(t, resultDto.Items)
+}
@@ -1,84 +1,197 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"user-api/audit"
 	userClient "user-api/client"
+	"user-api/notification"
+	"user-api/search"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"golang.org/x/crypto/bcrypt"
 
 	"user-api/dto"
 	"user-api/model"
 	e "user-api/utils/errors"
-)
+	"user-api/utils/token"
+	"user-api/utils/validation"
 
-type userService struct{}
+	log "github.com/sirupsen/logrus"
+)
 
-type userServiceInterface interface {
-	GetUsers() (dto.UsersDto, e.ApiError)
-	InsertUser(userDto *dto.UserDto) (*dto.UserDto, e.ApiError)
-	GetUserById(id int) (*dto.UserDto, e.ApiError)
-	DeleteUser(id int) error
-	UpdateUser(id int, userDto *dto.UserDto) (model.User, error)
+type userService struct {
+	users         userClient.UserClientInterface
+	refreshTokens userClient.RefreshTokenClientInterface
+	oneTimeTokens userClient.OneTimeTokenClientInterface
+	tokens        TokenServiceInterface
+	notifications notification.EventHandler
+	userSearch    search.Backend
+	settings      UserSettings
 }
 
-var (
-	UserService userServiceInterface
-	UserClient  userClient.UserClientInterface
+// Patch formats accepted by PatchUser, named after their media types.
+const (
+	MergePatch = "application/merge-patch+json"
+	JSONPatch  = "application/json-patch+json"
 )
 
-func init() {
-	UserService = &userService{}
-	UserClient = &userClient.UserClient{}
+type UserServiceInterface interface {
+	GetUsers(ctx context.Context, queryDto *dto.UserListQueryDto) (*dto.UsersPageDto, e.ApiError)
+	SearchUsers(ctx context.Context, searchDto *dto.UserSearchQueryDto) (*dto.UserSearchResultDto, e.ApiError)
+	InsertUser(ctx context.Context, userDto *dto.UserCreateDto) (*dto.UserDto, e.ApiError)
+	GetUserById(ctx context.Context, id int) (*dto.UserDto, e.ApiError)
+	DeleteUser(ctx context.Context, id int, version int) error
+	RestoreUser(ctx context.Context, id int) (*dto.UserDto, e.ApiError)
+	PurgeDeletedUsers(ctx context.Context) (int, e.ApiError)
+	UpdateUser(ctx context.Context, id int, version int, userDto *dto.UserUpdateDto) (*dto.UserDto, e.ApiError)
+	PatchUser(ctx context.Context, id int, version int, patch []byte, patchType string) (*dto.UserDto, e.ApiError)
+	Login(ctx context.Context, loginDto *dto.LoginDto) (*dto.TokenDto, e.ApiError)
+	ChangePassword(ctx context.Context, id int, passwordDto *dto.PasswordChangeDto) e.ApiError
+	ForgotPassword(ctx context.Context, forgotDto *dto.PasswordForgotDto) e.ApiError
+	ResetPassword(ctx context.Context, resetDto *dto.PasswordResetDto) e.ApiError
+	VerifyEmail(ctx context.Context, verificationToken string) e.ApiError
+}
 
+// UserSettings tunes the user service.
+type UserSettings struct {
+	// SearchLimit is the number of results of a search that doesn't set a limit.
+	SearchLimit int
+	// PasswordResetTTL is how long a password reset token can be redeemed.
+	PasswordResetTTL time.Duration
+	// EmailVerificationTTL is how long an email verification token can be redeemed.
+	EmailVerificationTTL time.Duration
+	// RequireVerifiedEmail blocks login until the user verifies the email address.
+	RequireVerifiedEmail bool
+	// DeletedUserRetention is how long a deleted user can be restored before it's purged.
+	DeletedUserRetention time.Duration
 }
 
-func (s *userService) GetUserById(id int) (*dto.UserDto, e.ApiError) {
-	user := UserClient.GetUserById(id)
-	if user.Id == 0 {
-		return nil, e.NewBadRequestApiError("Usuario no encontrado")
+func DefaultUserSettings() UserSettings {
+	return UserSettings{
+		SearchLimit:          20,
+		PasswordResetTTL:     time.Hour,
+		EmailVerificationTTL: 24 * time.Hour,
+		DeletedUserRetention: 30 * 24 * time.Hour,
 	}
+}
 
-	userDto := &dto.UserDto{
-		Name:     user.Name,
-		LastName: user.LastName,
-		UserName: user.UserName,
-		Phone:    user.Phone,
-		Address:  user.Address,
-		Email:    user.Email,
-		Id:       user.Id,
-		Type:     user.Type, // Suponiendo que user.Type es string
+func NewUserService(
+	users userClient.UserClientInterface,
+	refreshTokens userClient.RefreshTokenClientInterface,
+	oneTimeTokens userClient.OneTimeTokenClientInterface,
+	tokens TokenServiceInterface,
+	notifications notification.EventHandler,
+	userSearch search.Backend,
+	settings UserSettings,
+) UserServiceInterface {
+	return &userService{
+		users:         users,
+		refreshTokens: refreshTokens,
+		oneTimeTokens: oneTimeTokens,
+		tokens:        tokens,
+		notifications: notifications,
+		userSearch:    userSearch,
+		settings:      settings,
 	}
-	return userDto, nil
 }
 
-func (s *userService) GetUsers() (dto.UsersDto, e.ApiError) {
-	users := UserClient.GetUsers()
-	var usersDto dto.UsersDto
+func (s *userService) GetUserById(ctx context.Context, id int) (*dto.UserDto, e.ApiError) {
+	user, apiErr := s.getUser(ctx, id)
+	if apiErr != nil {
+		return nil, apiErr
+	}
 
-	for _, user := range users {
-		userDto := dto.UserDto{
-			Name:     user.Name,
-			LastName: user.LastName,
-			UserName: user.UserName,
-			Phone:    user.Phone,
-			Address:  user.Address,
-			Email:    user.Email,
-			Id:       user.Id,
-			Type:     user.Type, // Suponiendo que user.Type es string
-		}
-		usersDto = append(usersDto, userDto)
+	userDto := toUserDto(user)
+	return &userDto, nil
+}
+
+func (s *userService) GetUsers(ctx context.Context, queryDto *dto.UserListQueryDto) (*dto.UsersPageDto, e.ApiError) {
+	if apiErr := validation.Struct(queryDto); apiErr != nil {
+		return nil, apiErr
+	}
+
+	query := userClient.UserQuery{
+		Limit:       queryDto.Limit,
+		Offset:      queryDto.Offset,
+		Cursor:      queryDto.Cursor,
+		SortBy:      strings.TrimPrefix(queryDto.Sort, "-"),
+		Descending:  strings.HasPrefix(queryDto.Sort, "-"),
+		NamePrefix:  queryDto.Name,
+		EmailDomain: queryDto.EmailDomain,
+		Type:        queryDto.Type,
+	}
+	if query.Limit == 0 {
+		query.Limit = userClient.DefaultPageSize
+	}
+
+	page, err := s.users.GetUsers(ctx, query)
+	if err == userClient.ErrInvalidCursor {
+		return nil, e.NewBadRequestApiError(e.MsgInvalidCursor)
+	}
+	if err != nil {
+		return nil, clientApiError(err, e.MsgCouldNotGetUsers)
+	}
+
+	usersDto := dto.UsersDto{}
+	for _, user := range page.Users {
+		usersDto = append(usersDto, toUserDto(user))
 	}
-	return usersDto, nil
+
+	return &dto.UsersPageDto{
+		Items:      usersDto,
+		Total:      page.Total,
+		Limit:      query.Limit,
+		Offset:     query.Offset,
+		NextCursor: page.NextCursor,
+	}, nil
 }
 
-func (s *userService) InsertUser(userDto *dto.UserDto) (*dto.UserDto, e.ApiError) {
-	if UserClient.GetUserByEmail(userDto.Email) {
-		return nil, e.NewBadRequestApiError("El email ya está registrado")
+func (s *userService) SearchUsers(ctx context.Context, searchDto *dto.UserSearchQueryDto) (*dto.UserSearchResultDto, e.ApiError) {
+	if apiErr := validation.Struct(searchDto); apiErr != nil {
+		return nil, apiErr
+	}
+
+	limit := searchDto.Limit
+	if limit == 0 {
+		limit = s.settings.SearchLimit
+	}
+
+	hits, err := s.userSearch.Search(ctx, searchDto.Q, limit)
+	if err != nil {
+		return nil, clientApiError(err, e.MsgCouldNotSearch)
+	}
+
+	resultDto := dto.UserSearchResultDto{Items: []dto.UserSearchHitDto{}}
+	for _, hit := range hits {
+		resultDto.Items = append(resultDto.Items, dto.UserSearchHitDto{UserDto: toUserDto(hit.User), Score: hit.Score})
+	}
+
+	return &resultDto, nil
+}
+
+func (s *userService) InsertUser(ctx context.Context, userDto *dto.UserCreateDto) (*dto.UserDto, e.ApiError) {
+	if apiErr := validation.Struct(userDto); apiErr != nil {
+		return nil, apiErr
+	}
+
+	taken, err := s.users.EmailTaken(ctx, userDto.Email)
+	if err != nil {
+		return nil, clientApiError(err, e.MsgCouldNotRegisterUser)
+	}
+	if taken {
+		return nil, duplicateApiError("email")
 	}
 
 	hashedPassword, err := s.HashPassword(userDto.Password)
 	if err != nil {
-		return nil, e.NewBadRequestApiError("No se puede utilizar esa contraseña")
+		return nil, e.NewBadRequestApiError(e.MsgPasswordNotAllowed)
 	}
 
 	user := model.User{
@@ -89,16 +202,26 @@ func (s *userService) InsertUser(userDto *dto.UserDto) (*dto.UserDto, e.ApiError
 		Phone:    userDto.Phone,
 		Address:  userDto.Address,
 		Email:    userDto.Email,
-		Type:     userDto.Type, // Suponiendo que userDto.Type es string
+		Type:     false, // Signup never grants admin, whatever the caller sends
+
+		EmailVerified: false,
 	}
 
-	user = UserClient.InsertUser(user)
-	if user.Id == 0 {
-		return nil, e.NewBadRequestApiError("Nombre de usuario repetido")
+	entry := audit.NewEntry(ctx, model.AuditActionCreate, 0, nil, &user)
+	user, err = s.users.InsertUser(ctx, user, entry)
+	if err != nil {
+		return nil, clientApiError(err, e.MsgCouldNotRegisterUser)
 	}
 
-	userDto.Id = user.Id
-	return userDto, nil
+	s.indexUser(ctx, user)
+	s.publish(ctx, notification.Event{Kind: notification.EventUserRegistered, User: user})
+	if apiErr := s.requestEmailVerification(ctx, user); apiErr != nil {
+		// The account exists already, the user can ask for the token again later
+		log.Error("Error requesting email verification: ", apiErr.Error())
+	}
+
+	createdDto := toUserDto(user)
+	return &createdDto, nil
 }
 
 func (s *userService) HashPassword(password string) (string, error) {
@@ -113,21 +236,150 @@ func (s *userService) VerifyPassword(hashedPassword string, candidatePassword st
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(candidatePassword))
 }
 
-func (s *userService) DeleteUser(id int) error {
+// DeleteUser deletes the user if version is still its current version.
+func (s *userService) DeleteUser(ctx context.Context, id int, version int) error {
 
-	result := UserClient.DeleteUser(id)
+	// Loaded for the audit log, which keeps what the user was like
+	user, apiErr := s.getUser(ctx, id)
+	if apiErr != nil {
+		return apiErr
+	}
+	if user.Version != version {
+		return conflictApiError(id)
+	}
 
-	if result != nil {
-		return result
+	entry := audit.NewEntry(ctx, model.AuditActionDelete, id, &user, nil)
+	if err := s.users.DeleteUser(ctx, id, version, entry); err != nil {
+		return writeApiError(err, id, e.MsgCouldNotDeleteUser)
 	}
 
+	if err := s.userSearch.Remove(ctx, id); err != nil {
+		log.Error("Error removing user from the search index: ", err)
+	}
+
+	// A deleted user can't refresh its session anymore
+	if err := s.refreshTokens.RevokeUserRefreshTokens(ctx, id); err != nil {
+		log.Error("Error revoking the sessions of the deleted user: ", err)
+	}
 	return nil
 
 }
 
-func (s *userService) UpdateUser(id int, userDto *dto.UserDto) (model.User, error) {
+func (s *userService) RestoreUser(ctx context.Context, id int) (*dto.UserDto, e.ApiError) {
+	entry := audit.NewEntry(ctx, model.AuditActionRestore, id, nil, nil)
+	user, err := s.users.RestoreUser(ctx, id, entry)
+	if errors.Is(err, userClient.ErrNotFound) {
+		return nil, e.NewNotFoundApiError(e.MsgDeletedUserNotFound)
+	}
+	if err != nil {
+		return nil, clientApiError(err, e.MsgCouldNotRestoreUser)
+	}
+
+	s.indexUser(ctx, user)
+
+	restoredDto := toUserDto(user)
+	return &restoredDto, nil
+}
+
+// PurgeDeletedUsers permanently removes the users deleted more than
+// DeletedUserRetention ago, recording each purge in the audit log.
+func (s *userService) PurgeDeletedUsers(ctx context.Context) (int, e.ApiError) {
+	entry := audit.NewEntry(ctx, model.AuditActionPurge, 0, nil, nil)
+	purged, err := s.users.PurgeUsers(ctx, time.Now().Add(-s.settings.DeletedUserRetention), entry)
+	if err != nil {
+		return 0, clientApiError(err, e.MsgCouldNotPurgeUsers)
+	}
+	return purged, nil
+}
+
+// UpdateUser replaces the updatable fields of the user. version is the version of
+// the user the changes are based on, and the update fails with a 409 if the user
+// changed since.
+func (s *userService) UpdateUser(ctx context.Context, id int, version int, userDto *dto.UserUpdateDto) (*dto.UserDto, e.ApiError) {
 	// Check if the user exists
-	user := UserClient.GetUserById(id)
+	user, apiErr := s.getUser(ctx, id)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if user.Version != version {
+		return nil, conflictApiError(id)
+	}
+
+	return s.applyUpdate(ctx, user, userDto)
+}
+
+// PatchUser applies a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) to the
+// updatable fields of the user. Fields the patch doesn't mention are left untouched.
+// As in UpdateUser, the patch must be based on the current version of the user.
+func (s *userService) PatchUser(ctx context.Context, id int, version int, patch []byte, patchType string) (*dto.UserDto, e.ApiError) {
+	user, apiErr := s.getUser(ctx, id)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if user.Version != version {
+		return nil, conflictApiError(id)
+	}
+
+	document, err := json.Marshal(toUserUpdateDto(user))
+	if err != nil {
+		return nil, internalApiError(e.MsgCouldNotUpdateUser, err)
+	}
+
+	var patched []byte
+	switch patchType {
+	case MergePatch:
+		patched, err = jsonpatch.MergePatch(document, patch)
+	case JSONPatch:
+		var operations jsonpatch.Patch
+		if operations, err = jsonpatch.DecodePatch(patch); err == nil {
+			patched, err = operations.Apply(document)
+		}
+	default:
+		return nil, e.NewApiError(e.MsgUnsupportedPatchType, "unsupported_media_type", http.StatusUnsupportedMediaType, e.CauseList{})
+	}
+	if err != nil {
+		return nil, e.NewBadRequestApiError(e.MsgInvalidPatch, err.Error())
+	}
+
+	// Fields outside UserUpdateDto (password, type...) can't be patched
+	var userDto dto.UserUpdateDto
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&userDto); err != nil {
+		return nil, e.NewBadRequestApiError(e.MsgInvalidPatch, err.Error())
+	}
+
+	return s.applyUpdate(ctx, user, &userDto)
+}
+
+// applyUpdate validates the new values of the user, checks that a changed email or
+// username is still free and saves the user.
+func (s *userService) applyUpdate(ctx context.Context, user model.User, userDto *dto.UserUpdateDto) (*dto.UserDto, e.ApiError) {
+	if apiErr := validation.Struct(userDto); apiErr != nil {
+		return nil, apiErr
+	}
+	before := user
+
+	emailChanged := user.Email != userDto.Email
+	if emailChanged {
+		taken, err := s.users.EmailTaken(ctx, userDto.Email)
+		if err != nil {
+			return nil, clientApiError(err, e.MsgCouldNotUpdateUser)
+		}
+		if taken {
+			return nil, duplicateApiError("email")
+		}
+	}
+
+	if user.UserName != userDto.UserName {
+		_, err := s.users.GetUserByUsername(ctx, userDto.UserName)
+		if err == nil {
+			return nil, duplicateApiError("user_name")
+		}
+		if !errors.Is(err, userClient.ErrNotFound) {
+			return nil, clientApiError(err, e.MsgCouldNotUpdateUser)
+		}
+	}
 
 	// Update the user's fields with the new data from userDto
 	user.Name = userDto.Name
@@ -137,10 +389,306 @@ func (s *userService) UpdateUser(id int, userDto *dto.UserDto) (model.User, erro
 	user.Phone = userDto.Phone
 	user.Address = userDto.Address
 
+	// A new address has to be verified again
+	if emailChanged {
+		user.EmailVerified = false
+	}
+
 	// Save the updated user to the database
-	if err := UserClient.UpdateUser(user); err != nil {
-		return user, err
+	if err := s.users.UpdateUser(ctx, user, updateEntry(ctx, before, user)); err != nil {
+		return nil, writeApiError(err, user.Id, e.MsgCouldNotUpdateUser)
+	}
+	user.Version++
+	s.indexUser(ctx, user)
+
+	if emailChanged {
+		// The tokens sent to the old address can't verify the new one
+		if err := s.oneTimeTokens.RevokeUserOneTimeTokens(ctx, user.Id, model.PurposeEmailVerification); err != nil {
+			log.Error("Error revoking email verification tokens: ", err)
+		}
+		if apiErr := s.requestEmailVerification(ctx, user); apiErr != nil {
+			log.Error("Error requesting email verification: ", apiErr.Error())
+		}
+	}
+
+	updatedDto := toUserDto(user)
+	return &updatedDto, nil
+}
+
+// passwordResetTimeout bounds issuing a password reset token once ForgotPassword
+// has answered.
+const passwordResetTimeout = 10 * time.Second
+
+// dummyPasswordHash is a bcrypt hash with the cost of HashPassword that no
+// password matches, compared against when a login doesn't exist.
+const dummyPasswordHash = "$2a$10$jyIdYhcvVT4fxb046FCueOKAxaBl/WhAJjDYQSjtlgdUHjfyUBi8a"
+
+func (s *userService) Login(ctx context.Context, loginDto *dto.LoginDto) (*dto.TokenDto, e.ApiError) {
+	var user model.User
+	var err error
+
+	// The login field accepts either the username or the email
+	if strings.Contains(loginDto.Login, "@") {
+		user, err = s.users.FindUserByEmail(ctx, loginDto.Login)
+	} else {
+		user, err = s.users.GetUserByUsername(ctx, loginDto.Login)
+	}
+
+	if err != nil && !errors.Is(err, userClient.ErrNotFound) {
+		return nil, clientApiError(err, e.MsgCouldNotLogin)
+	}
+	if err != nil {
+		// Compared anyway, so that unknown logins take as long as wrong passwords
+		s.VerifyPassword(dummyPasswordHash, loginDto.Password)
+		return nil, e.NewUnauthorizedApiError(e.MsgWrongCredentials)
+	}
+	if s.VerifyPassword(user.Password, loginDto.Password) != nil {
+		return nil, e.NewUnauthorizedApiError(e.MsgWrongCredentials)
+	}
+
+	if s.settings.RequireVerifiedEmail && !user.EmailVerified {
+		return nil, e.NewForbiddenApiError(e.MsgEmailNotVerified)
+	}
+
+	return s.tokens.IssueTokens(ctx, user)
+}
+
+func (s *userService) ChangePassword(ctx context.Context, id int, passwordDto *dto.PasswordChangeDto) e.ApiError {
+	if apiErr := validation.Struct(passwordDto); apiErr != nil {
+		return apiErr
+	}
+
+	user, apiErr := s.getUser(ctx, id)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	if s.VerifyPassword(user.Password, passwordDto.CurrentPassword) != nil {
+		return e.NewBadRequestApiError(e.MsgWrongCurrentPassword)
+	}
+
+	return s.setPassword(ctx, user, passwordDto.NewPassword)
+}
+
+// ForgotPassword sends a reset token to the user. It succeeds even if the email
+// is not registered, and the token is issued and sent after the response, so the
+// endpoint can't be used to discover accounts by its answer nor by its timing.
+func (s *userService) ForgotPassword(ctx context.Context, forgotDto *dto.PasswordForgotDto) e.ApiError {
+	if apiErr := validation.Struct(forgotDto); apiErr != nil {
+		return apiErr
+	}
+
+	user, err := s.users.FindUserByEmail(ctx, forgotDto.Email)
+	if errors.Is(err, userClient.ErrNotFound) {
+		log.Debug("Password reset requested for unknown email")
+		return nil
+	}
+	if err != nil {
+		return clientApiError(err, e.MsgCouldNotResetPassword)
+	}
+
+	go s.sendPasswordReset(context.WithoutCancel(ctx), user)
+	return nil
+}
+
+// sendPasswordReset issues a password reset token and sends it to the user. It
+// runs after ForgotPassword answered, so failures are only logged.
+func (s *userService) sendPasswordReset(ctx context.Context, user model.User) {
+	ctx, cancel := context.WithTimeout(ctx, passwordResetTimeout)
+	defer cancel()
+
+	resetToken, apiErr := s.issueOneTimeToken(ctx, user, model.PurposePasswordReset, s.settings.PasswordResetTTL)
+	if apiErr != nil {
+		log.Error("Error issuing password reset token: ", apiErr.Error())
+		return
+	}
+	s.publish(ctx, notification.Event{Kind: notification.EventPasswordResetRequested, User: user, Token: resetToken})
+}
+
+func (s *userService) ResetPassword(ctx context.Context, resetDto *dto.PasswordResetDto) e.ApiError {
+	if apiErr := validation.Struct(resetDto); apiErr != nil {
+		return apiErr
+	}
+
+	invalidToken := e.NewBadRequestApiError(e.MsgInvalidResetToken)
+
+	user, apiErr := s.redeemOneTimeToken(ctx, resetDto.Token, model.PurposePasswordReset, invalidToken)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	return s.setPassword(ctx, user, resetDto.NewPassword)
+}
+
+func (s *userService) VerifyEmail(ctx context.Context, verificationToken string) e.ApiError {
+	invalidToken := e.NewBadRequestApiError(e.MsgInvalidVerificationToken)
+
+	user, apiErr := s.redeemOneTimeToken(ctx, verificationToken, model.PurposeEmailVerification, invalidToken)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	before := user
+	user.EmailVerified = true
+	if err := s.users.UpdateUser(ctx, user, updateEntry(ctx, before, user)); err != nil {
+		return writeApiError(err, user.Id, e.MsgCouldNotVerifyEmail)
+	}
+	s.indexUser(ctx, user)
+	return nil
+}
+
+// requestEmailVerification sends the user a token to confirm the email address.
+func (s *userService) requestEmailVerification(ctx context.Context, user model.User) e.ApiError {
+	verificationToken, apiErr := s.issueOneTimeToken(ctx, user, model.PurposeEmailVerification, s.settings.EmailVerificationTTL)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	s.publish(ctx, notification.Event{Kind: notification.EventEmailVerificationRequested, User: user, Token: verificationToken})
+	return nil
+}
+
+// issueOneTimeToken stores the hash of a new one-time token and returns the raw token.
+func (s *userService) issueOneTimeToken(ctx context.Context, user model.User, purpose string, ttl time.Duration) (string, e.ApiError) {
+	rawToken, err := token.NewOpaque()
+	if err != nil {
+		return "", internalApiError(e.MsgCouldNotGenerateToken, err)
+	}
+
+	_, err = s.oneTimeTokens.InsertOneTimeToken(ctx, model.OneTimeToken{
+		UserId:    user.Id,
+		Purpose:   purpose,
+		TokenHash: token.Hash(rawToken),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", clientApiError(err, e.MsgCouldNotGenerateToken)
+	}
+
+	return rawToken, nil
+}
+
+// redeemOneTimeToken marks a valid token as used and returns its user. Unknown,
+// used and expired tokens all get the same invalidToken error, and so do email
+// verification tokens sent to an address the user no longer has.
+func (s *userService) redeemOneTimeToken(ctx context.Context, rawToken string, purpose string, invalidToken e.ApiError) (model.User, e.ApiError) {
+	stored, err := s.oneTimeTokens.GetOneTimeTokenByHash(ctx, token.Hash(rawToken), purpose)
+	if err != nil && !errors.Is(err, userClient.ErrNotFound) {
+		return model.User{}, clientApiError(err, e.MsgCouldNotValidateToken)
+	}
+	if err != nil || stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return model.User{}, invalidToken
+	}
+
+	used, err := s.oneTimeTokens.UseOneTimeToken(ctx, stored.Id)
+	if err != nil {
+		return model.User{}, clientApiError(err, e.MsgCouldNotValidateToken)
+	}
+	if !used {
+		return model.User{}, invalidToken
+	}
+
+	user, err := s.users.GetUserById(ctx, stored.UserId)
+	if errors.Is(err, userClient.ErrNotFound) {
+		return model.User{}, invalidToken
+	}
+	if err != nil {
+		return model.User{}, clientApiError(err, e.MsgCouldNotValidateToken)
+	}
+	if purpose == model.PurposeEmailVerification && stored.Email != user.Email {
+		return model.User{}, invalidToken
 	}
 
 	return user, nil
 }
+
+// setPassword stores the new password hash and ends every open session of the user.
+// The password must have been validated against the password policy already.
+func (s *userService) setPassword(ctx context.Context, user model.User, password string) e.ApiError {
+	hashedPassword, err := s.HashPassword(password)
+	if err != nil {
+		return e.NewBadRequestApiError(e.MsgPasswordNotAllowed)
+	}
+
+	before := user
+	user.Password = hashedPassword
+	if err := s.users.UpdateUser(ctx, user, updateEntry(ctx, before, user)); err != nil {
+		return writeApiError(err, user.Id, e.MsgCouldNotUpdatePassword)
+	}
+
+	if err := s.refreshTokens.RevokeUserRefreshTokens(ctx, user.Id); err != nil {
+		return clientApiError(err, e.MsgCouldNotCloseSessions)
+	}
+
+	return nil
+}
+
+// getUser loads a user that isn't deleted, with a 404 if there is none.
+func (s *userService) getUser(ctx context.Context, id int) (model.User, e.ApiError) {
+	user, err := s.users.GetUserById(ctx, id)
+	if errors.Is(err, userClient.ErrNotFound) {
+		return user, e.NewNotFoundApiError(e.MsgUserNotFound)
+	}
+	if err != nil {
+		return user, clientApiError(err, e.MsgCouldNotGetUser)
+	}
+	return user, nil
+}
+
+// indexUser refreshes the user in the search backend. A stale index only affects
+// search results, so failures are logged and the operation goes on.
+func (s *userService) indexUser(ctx context.Context, user model.User) {
+	if err := s.userSearch.Index(ctx, user); err != nil {
+		log.Error("Error indexing user for search: ", err)
+	}
+}
+
+// updateEntry is the audit entry of changing before into after, which the client
+// records together with the update. An update that changed nothing gets an empty
+// entry and is left out of the audit log.
+func updateEntry(ctx context.Context, before model.User, after model.User) model.AuditEntry {
+	entry := audit.NewEntry(ctx, model.AuditActionUpdate, after.Id, &before, &after)
+	if len(entry.Changes) == 0 {
+		return model.AuditEntry{}
+	}
+	return entry
+}
+
+// publish hands a user event to the notification subsystem. main wraps it in
+// notification.Async, so delivery happens after the response, and failures are
+// logged and never fail the operation that triggered the event.
+func (s *userService) publish(ctx context.Context, event notification.Event) {
+	if err := s.notifications.Handle(ctx, event); err != nil {
+		log.WithField("event", event.Kind).Error("Error sending notification: ", err)
+	}
+}
+
+// toUserDto maps a user to its public representation, leaving the password hash behind.
+func toUserDto(user model.User) dto.UserDto {
+	return dto.UserDto{
+		Id:       user.Id,
+		Name:     user.Name,
+		LastName: user.LastName,
+		UserName: user.UserName,
+		Phone:    user.Phone,
+		Address:  user.Address,
+		Email:    user.Email,
+		Type:     user.Type,
+
+		EmailVerified: user.EmailVerified,
+
+		Version: user.Version,
+	}
+}
+
+func toUserUpdateDto(user model.User) dto.UserUpdateDto {
+	return dto.UserUpdateDto{
+		Name:     user.Name,
+		LastName: user.LastName,
+		UserName: user.UserName,
+		Phone:    user.Phone,
+		Address:  user.Address,
+		Email:    user.Email,
+	}
+}
@@ -1,54 +1,131 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
+	userClient "user-api/client"
 	"user-api/dto"
 	"user-api/model"
+	"user-api/notification"
+	"user-api/search"
 	e "user-api/utils/errors"
+	"user-api/utils/token"
+	"user-api/utils/validation"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 )
 
+var ctx = context.Background()
+
 // Mock the userClient to simulate client responses
 type MockUserClient struct {
 	mock.Mock
+	// audited are the audit entries of the writes that succeeded, as the client
+	// would have recorded them
+	audited []model.AuditEntry
+}
+
+func (m *MockUserClient) audit(entry model.AuditEntry, targetId int, err error) {
+	if entry.Action == "" || err != nil {
+		return
+	}
+	entry.TargetId = targetId
+	m.audited = append(m.audited, entry)
+}
+
+func (m *MockUserClient) GetUserById(ctx context.Context, id int) (model.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(model.User), args.Error(1)
 }
 
-func (m *MockUserClient) GetUserById(id int) model.User {
-	args := m.Called(id)
-	return args.Get(0).(model.User)
+func (m *MockUserClient) GetUsers(ctx context.Context, query userClient.UserQuery) (userClient.UserPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(userClient.UserPage), args.Error(1)
 }
 
-func (m *MockUserClient) GetUsers() model.Users {
-	args := m.Called()
-	return args.Get(0).(model.Users)
+func (m *MockUserClient) EmailTaken(ctx context.Context, email string) (bool, error) {
+	args := m.Called(ctx, email)
+	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockUserClient) GetUserByEmail(email string) bool {
-	args := m.Called(email)
-	return args.Bool(0)
+func (m *MockUserClient) GetUserByUsername(ctx context.Context, username string) (model.User, error) {
+	args := m.Called(ctx, username)
+	return args.Get(0).(model.User), args.Error(1)
 }
 
-func (m *MockUserClient) InsertUser(user model.User) model.User {
-	args := m.Called(user)
-	return args.Get(0).(model.User)
+func (m *MockUserClient) FindUserByEmail(ctx context.Context, email string) (model.User, error) {
+	args := m.Called(ctx, email)
+	return args.Get(0).(model.User), args.Error(1)
 }
 
-func (m *MockUserClient) DeleteUser(id int) error {
-	args := m.Called(id)
+func (m *MockUserClient) InsertUser(ctx context.Context, user model.User, entry model.AuditEntry) (model.User, error) {
+	args := m.Called(ctx, user, entry)
+	m.audit(entry, args.Get(0).(model.User).Id, args.Error(1))
+	return args.Get(0).(model.User), args.Error(1)
+}
+
+func (m *MockUserClient) DeleteUser(ctx context.Context, id int, version int, entry model.AuditEntry) error {
+	args := m.Called(ctx, id, version, entry)
+	m.audit(entry, id, args.Error(0))
 	return args.Error(0)
 }
 
-func (m *MockUserClient) UpdateUser(user model.User) error {
-	args := m.Called(user)
+func (m *MockUserClient) RestoreUser(ctx context.Context, id int, entry model.AuditEntry) (model.User, error) {
+	args := m.Called(ctx, id, entry)
+	m.audit(entry, id, args.Error(1))
+	return args.Get(0).(model.User), args.Error(1)
+}
+
+func (m *MockUserClient) PurgeUsers(ctx context.Context, deletedBefore time.Time, entry model.AuditEntry) (int, error) {
+	args := m.Called(ctx, deletedBefore, entry)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserClient) UpdateUser(ctx context.Context, user model.User, entry model.AuditEntry) error {
+	args := m.Called(ctx, user, entry)
+	m.audit(entry, user.Id, args.Error(0))
 	return args.Error(0)
 }
 
+// userServiceMocks are the collaborators of a user service under test.
+type userServiceMocks struct {
+	users         *MockUserClient
+	refreshTokens *MockRefreshTokenClient
+	oneTimeTokens *MockOneTimeTokenClient
+	notifier      *notification.MemoryNotifier
+}
+
+// newTestUserService builds a user service on fresh mocks, with a real token service
+// and an empty in-memory search index.
+func newTestUserService() (*userService, userServiceMocks) {
+	mocks := userServiceMocks{
+		users:         new(MockUserClient),
+		refreshTokens: new(MockRefreshTokenClient),
+		oneTimeTokens: new(MockOneTimeTokenClient),
+		notifier:      &notification.MemoryNotifier{},
+	}
+
+	userService := NewUserService(
+		mocks.users,
+		mocks.refreshTokens,
+		mocks.oneTimeTokens,
+		NewTokenService(mocks.users, mocks.refreshTokens, testSigner, testRefreshTokenTTL),
+		notification.Dispatcher{Notifier: mocks.notifier},
+		search.NewMemoryIndex(nil),
+		DefaultUserSettings(),
+	).(*userService)
+
+	return userService, mocks
+}
+
 func TestGetUserById_Success(t *testing.T) {
 
-	mockUserClient := new(MockUserClient)
-	UserClient = mockUserClient
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
 
 	mockUser := model.User{
 		Id:       1,
@@ -58,9 +135,9 @@ func TestGetUserById_Success(t *testing.T) {
 		Email:    "jdoe@example.com",
 	}
 
-	mockUserClient.On("GetUserById", 1).Return(mockUser)
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(mockUser, nil)
 
-	userDto, err := UserService.GetUserById(1)
+	userDto, err := userService.GetUserById(ctx, 1)
 
 	assert.Nil(t, err)
 	assert.Equal(t, "John", userDto.Name)
@@ -70,64 +147,193 @@ func TestGetUserById_Success(t *testing.T) {
 
 func TestGetUserById_NotFound(t *testing.T) {
 
-	mockUserClient := new(MockUserClient)
-	UserClient = mockUserClient
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
 
-	mockUserClient.On("GetUserById", 2).Return(model.User{Id: 0})
+	mockUserClient.On("GetUserById", mock.Anything, 2).Return(model.User{}, userClient.ErrNotFound)
 
-	userDto, err := UserService.GetUserById(2)
+	userDto, err := userService.GetUserById(ctx, 2)
 
 	message := string(err.Message())
 
 	assert.Nil(t, userDto)
+	assert.Equal(t, 404, err.Status())
 	assert.Equal(t, "Usuario no encontrado", message)
 	mockUserClient.AssertExpectations(t)
 }
 
+func TestGetUserById_DatabaseUnavailable(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(model.User{}, &userClient.TransientError{Err: context.DeadlineExceeded})
+
+	userDto, err := userService.GetUserById(ctx, 1)
+
+	assert.Nil(t, userDto)
+	assert.Equal(t, 503, err.Status())
+	assert.Equal(t, "service_unavailable", err.Code())
+	assert.Empty(t, err.Cause())
+	mockUserClient.AssertExpectations(t)
+}
+
 func TestGetUsers(t *testing.T) {
 
-	mockUserClient := new(MockUserClient)
-	UserClient = mockUserClient
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
 
 	mockUsers := model.Users{
 		{Id: 1, Name: "John", LastName: "Doe", UserName: "jdoe"},
 		{Id: 2, Name: "Jane", LastName: "Smith", UserName: "jsmith"},
 	}
 
-	mockUserClient.On("GetUsers").Return(mockUsers)
+	mockUserClient.On("GetUsers", mock.Anything, userClient.UserQuery{Limit: userClient.DefaultPageSize}).Return(userClient.UserPage{Users: mockUsers, Total: 2}, nil)
+
+	usersPageDto, err := userService.GetUsers(ctx, &dto.UserListQueryDto{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(usersPageDto.Items))
+	assert.Equal(t, "John", usersPageDto.Items[0].Name)
+	assert.Equal(t, "Jane", usersPageDto.Items[1].Name)
+	assert.Equal(t, 2, usersPageDto.Total)
+	assert.Empty(t, usersPageDto.NextCursor)
+	mockUserClient.AssertExpectations(t)
+}
+
+func TestGetUsers_QueryMapping(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
 
-	usersDto, err := UserService.GetUsers()
+	isAdmin := false
+	expectedQuery := userClient.UserQuery{
+		Limit:       10,
+		Cursor:      "cursor",
+		SortBy:      "last_name",
+		Descending:  true,
+		NamePrefix:  "Jo",
+		EmailDomain: "example.com",
+		Type:        &isAdmin,
+	}
+	mockUserClient.On("GetUsers", mock.Anything, expectedQuery).Return(userClient.UserPage{Total: 0}, nil)
+
+	usersPageDto, err := userService.GetUsers(ctx, &dto.UserListQueryDto{
+		Limit:       10,
+		Cursor:      "cursor",
+		Sort:        "-last_name",
+		Name:        "Jo",
+		EmailDomain: "example.com",
+		Type:        &isAdmin,
+	})
 
 	assert.Nil(t, err)
-	assert.Equal(t, 2, len(usersDto))
-	assert.Equal(t, "John", usersDto[0].Name)
-	assert.Equal(t, "Jane", usersDto[1].Name)
+	assert.NotNil(t, usersPageDto.Items)
 	mockUserClient.AssertExpectations(t)
 }
 
+func TestGetUsers_InvalidQuery(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	usersPageDto, err := userService.GetUsers(ctx, &dto.UserListQueryDto{Limit: 1000, Sort: "password"})
+
+	assert.Nil(t, usersPageDto)
+	assert.Equal(t, "validation_error", err.Code())
+	assert.Len(t, err.Cause(), 2)
+	mockUserClient.AssertNotCalled(t, "GetUsers", mock.Anything, mock.Anything)
+}
+
+func TestGetUsers_InvalidCursor(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUsers", mock.Anything, mock.Anything).Return(userClient.UserPage{}, userClient.ErrInvalidCursor)
+
+	usersPageDto, err := userService.GetUsers(ctx, &dto.UserListQueryDto{Cursor: "garbage"})
+
+	assert.Nil(t, usersPageDto)
+	assert.Equal(t, "Cursor inválido", err.Message())
+}
+
 func TestInsertUser_EmailExists(t *testing.T) {
 
-	mockUserClient := new(MockUserClient)
-	UserClient = mockUserClient
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
 
-	mockUserDto := &dto.UserDto{Email: "jdoe@example.com"}
-	mockUserClient.On("GetUserByEmail", "jdoe@example.com").Return(true)
+	mockUserDto := &dto.UserCreateDto{
+		Name:     "John",
+		LastName: "Doe",
+		UserName: "jdoe",
+		Email:    "jdoe@example.com",
+		Password: "password123",
+	}
+	mockUserClient.On("EmailTaken", mock.Anything, "jdoe@example.com").Return(true, nil)
 
-	user, err := UserService.InsertUser(mockUserDto)
+	user, err := userService.InsertUser(ctx, mockUserDto)
 
 	message := string(err.Message())
 
 	assert.Nil(t, user)
+	assert.Equal(t, 409, err.Status())
 	assert.Equal(t, "El email ya está registrado", message)
 	mockUserClient.AssertExpectations(t)
 }
 
+func TestInsertUser_UsernameTaken(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserDto := &dto.UserCreateDto{
+		Name:     "John",
+		LastName: "Doe",
+		UserName: "jdoe",
+		Email:    "jdoe@example.com",
+		Password: "password123",
+	}
+	mockUserClient.On("EmailTaken", mock.Anything, "jdoe@example.com").Return(false, nil)
+	mockUserClient.On("InsertUser", mock.Anything, mock.Anything, mock.Anything).Return(model.User{}, &userClient.DuplicateKeyError{Table: "users", Column: "user_name"})
+
+	user, err := userService.InsertUser(ctx, mockUserDto)
+
+	assert.Nil(t, user)
+	assert.Equal(t, 409, err.Status())
+	assert.Equal(t, "Nombre de usuario repetido", err.Message())
+	assert.Equal(t, "username", err.Cause()[0].(validation.FieldError).Field)
+	mockUserClient.AssertExpectations(t)
+}
+
+func TestInsertUser_DatabaseUnavailable(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserDto := &dto.UserCreateDto{
+		Name:     "John",
+		LastName: "Doe",
+		UserName: "jdoe",
+		Email:    "jdoe@example.com",
+		Password: "password123",
+	}
+	// A failed lookup must not be read as a free email
+	mockUserClient.On("EmailTaken", mock.Anything, "jdoe@example.com").Return(false, &userClient.TransientError{Err: errors.New("connection refused")})
+
+	user, err := userService.InsertUser(ctx, mockUserDto)
+
+	assert.Nil(t, user)
+	assert.Equal(t, 503, err.Status())
+	mockUserClient.AssertNotCalled(t, "InsertUser", mock.Anything, mock.Anything)
+}
+
 func TestInsertUser_Success(t *testing.T) {
 
-	mockUserClient := new(MockUserClient)
-	UserClient = mockUserClient
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
 
-	mockUserDto := &dto.UserDto{
+	mockUserDto := &dto.UserCreateDto{
 		Name:     "John",
 		LastName: "Doe",
 		UserName: "jdoe",
@@ -135,61 +341,331 @@ func TestInsertUser_Success(t *testing.T) {
 		Password: "password123",
 	}
 
-	mockUserClient.On("GetUserByEmail", "jdoe@example.com").Return(false)
-	mockUserClient.On("InsertUser", mock.Anything).Return(model.User{Id: 1})
+	mockOneTimeTokenClient := mocks.oneTimeTokens
+	notifier := mocks.notifier
+
+	mockUserClient.On("EmailTaken", mock.Anything, "jdoe@example.com").Return(false, nil)
+	mockUserClient.On("InsertUser", mock.Anything, mock.Anything, mock.Anything).Return(model.User{Id: 1, Name: "John", Email: "jdoe@example.com"}, nil)
+	mockOneTimeTokenClient.On("InsertOneTimeToken", mock.Anything, mock.MatchedBy(func(oneTimeToken model.OneTimeToken) bool {
+		return oneTimeToken.UserId == 1 && oneTimeToken.Purpose == model.PurposeEmailVerification
+	})).Return(model.OneTimeToken{Id: 1}, nil)
 
-	user, err := UserService.InsertUser(mockUserDto)
+	user, err := userService.InsertUser(ctx, mockUserDto)
 
 	assert.Nil(t, err)
 	assert.NotNil(t, user)
 	assert.Equal(t, 1, user.Id)
+	assert.False(t, user.EmailVerified)
+
+	messages := notifier.Messages()
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "Bienvenido/a John", messages[0].Subject)
+	assert.Equal(t, "Verifica tu email", messages[1].Subject)
+	mockUserClient.AssertExpectations(t)
+	mockOneTimeTokenClient.AssertExpectations(t)
+}
+
+// hangingNotifier doesn't deliver until released, like an unresponsive relay.
+type hangingNotifier struct {
+	release chan struct{}
+}
+
+func (n hangingNotifier) Send(ctx context.Context, message notification.Message) error {
+	select {
+	case <-n.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestInsertUser_SlowNotifier(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	notifier := hangingNotifier{release: make(chan struct{})}
+	defer close(notifier.release)
+	userService.notifications = notification.Async{Handler: notification.Dispatcher{Notifier: notifier}}
+
+	mockUserClient.On("EmailTaken", mock.Anything, "jdoe@example.com").Return(false, nil)
+	mockUserClient.On("InsertUser", mock.Anything, mock.Anything, mock.Anything).Return(model.User{Id: 1, Name: "John", Email: "jdoe@example.com"}, nil)
+	mocks.oneTimeTokens.On("InsertOneTimeToken", mock.Anything, mock.Anything).Return(model.OneTimeToken{Id: 1}, nil)
+
+	requestCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Delivery doesn't use up the deadline of the request
+	user, err := userService.InsertUser(requestCtx, &dto.UserCreateDto{Name: "John", LastName: "Doe", UserName: "jdoe", Email: "jdoe@example.com", Password: "password123"})
+
+	assert.Nil(t, err)
+	assert.NotNil(t, user)
+	assert.NoError(t, requestCtx.Err())
+	assert.Len(t, auditEntries(mocks), 1)
+}
+
+func TestInsertUser_CannotSelfAssignAdmin(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserDto := &dto.UserCreateDto{
+		Name:     "John",
+		LastName: "Doe",
+		UserName: "jdoe",
+		Email:    "jdoe@example.com",
+		Password: "password123",
+	}
+
+	mockOneTimeTokenClient := mocks.oneTimeTokens
+
+	mockUserClient.On("EmailTaken", mock.Anything, "jdoe@example.com").Return(false, nil)
+	mockUserClient.On("InsertUser", mock.Anything, mock.MatchedBy(func(user model.User) bool {
+		return !user.Type && !user.EmailVerified
+	}), mock.Anything).Return(model.User{Id: 1}, nil)
+	mockOneTimeTokenClient.On("InsertOneTimeToken", mock.Anything, mock.Anything).Return(model.OneTimeToken{Id: 1}, nil)
+
+	user, err := userService.InsertUser(ctx, mockUserDto)
+
+	assert.Nil(t, err)
+	assert.False(t, user.Type)
 	mockUserClient.AssertExpectations(t)
 }
 
 func TestDeleteUser_Success(t *testing.T) {
 
-	mockUserClient := new(MockUserClient)
-	UserClient = mockUserClient
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
 
-	mockUserClient.On("DeleteUser", 1).Return(nil)
+	mockRefreshTokenClient := mocks.refreshTokens
 
-	err := UserService.DeleteUser(1)
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(model.User{Id: 1, UserName: "jdoe", Version: 1}, nil)
+	mockUserClient.On("DeleteUser", mock.Anything, 1, 1, mock.Anything).Return(nil)
+	mockRefreshTokenClient.On("RevokeUserRefreshTokens", mock.Anything, 1).Return(nil)
+
+	err := userService.DeleteUser(ctx, 1, 1)
 
 	assert.Nil(t, err)
 	mockUserClient.AssertExpectations(t)
+	mockRefreshTokenClient.AssertExpectations(t)
+}
+
+func TestRestoreUser_Success(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("RestoreUser", mock.Anything, 1, mock.Anything).Return(model.User{Id: 1, UserName: "jdoe", Password: "hash"}, nil)
+
+	userDto, err := userService.RestoreUser(ctx, 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "jdoe", userDto.UserName)
+	mockUserClient.AssertExpectations(t)
+}
+
+func TestRestoreUser_NotDeleted(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("RestoreUser", mock.Anything, 2, mock.Anything).Return(model.User{}, userClient.ErrNotFound)
+
+	userDto, err := userService.RestoreUser(ctx, 2)
+
+	assert.Nil(t, userDto)
+	assert.Equal(t, 404, err.Status())
+	assert.Equal(t, "Usuario eliminado no encontrado", err.Message())
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+	userService.settings.DeletedUserRetention = 48 * time.Hour
+
+	// The cutoff is the retention period before now
+	mockUserClient.On("PurgeUsers", mock.Anything, mock.MatchedBy(func(deletedBefore time.Time) bool {
+		cutoff := time.Since(deletedBefore)
+		return cutoff >= 48*time.Hour && cutoff < 48*time.Hour+time.Minute
+	}), mock.MatchedBy(func(entry model.AuditEntry) bool {
+		return entry.Action == model.AuditActionPurge && entry.ActorId == nil && len(entry.Changes) == 0
+	})).Return(3, nil)
+
+	purged, err := userService.PurgeDeletedUsers(ctx)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, purged)
+	mockUserClient.AssertExpectations(t)
 }
 
 func TestDeleteUser_Failure(t *testing.T) {
 
-	mockUserClient := new(MockUserClient)
-	UserClient = mockUserClient
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
 
-	mockUserClient.On("DeleteUser", 2).Return(e.NewBadRequestApiError("Error deleting user"))
+	mockUserClient.On("GetUserById", mock.Anything, 2).Return(model.User{}, userClient.ErrNotFound)
+	mockUserClient.On("GetUserById", mock.Anything, 3).Return(model.User{Id: 3, Version: 1}, nil)
+	mockUserClient.On("DeleteUser", mock.Anything, 3, 1, mock.Anything).Return(errors.New("disk I/O error"))
 
-	err := UserService.DeleteUser(2)
+	err := userService.DeleteUser(ctx, 2, 1)
 
 	message := string(err.Error())
 
 	assert.NotNil(t, err)
-	assert.Equal(t, "Message: Error deleting user;Error Code: bad_request;Status: 400;Cause: []", message)
+	assert.Equal(t, "Message: Usuario no encontrado;Error Code: not_found;Status: 404;Cause: []", message)
+
+	// Test case: the driver error is logged, not returned
+	err = userService.DeleteUser(ctx, 3, 1)
+	assert.Equal(t, 500, err.(e.ApiError).Status())
+	assert.Empty(t, err.(e.ApiError).Cause())
+	assert.NotContains(t, err.Error(), "disk I/O error")
 	mockUserClient.AssertExpectations(t)
 }
 
 func TestUpdateUser_Success(t *testing.T) {
 
-	mockUserClient := new(MockUserClient)
-	UserClient = mockUserClient
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
 
-	mockUser := model.User{Id: 1, Name: "John", LastName: "Doe", UserName: "jdoe"}
-	mockUserDto := &dto.UserDto{Name: "John Updated", LastName: "Doe Updated", UserName: "jdoeupdated"}
+	mockUser := model.User{Id: 1, Name: "John", LastName: "Doe", UserName: "jdoe", Email: "jdoe@example.com", Version: 1}
+	mockUserDto := &dto.UserUpdateDto{Name: "John Updated", LastName: "Doe Updated", UserName: "jdoeupdated", Email: "jdoe@example.com"}
 
-	mockUserClient.On("GetUserById", 1).Return(mockUser)
-	mockUserClient.On("UpdateUser", mock.Anything).Return(nil)
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(mockUser, nil)
+	mockUserClient.On("GetUserByUsername", mock.Anything, "jdoeupdated").Return(model.User{}, userClient.ErrNotFound)
+	mockUserClient.On("UpdateUser", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-	updatedUser, err := UserService.UpdateUser(1, mockUserDto)
+	updatedUser, err := userService.UpdateUser(ctx, 1, 1, mockUserDto)
 
 	assert.Nil(t, err)
 	assert.Equal(t, "John Updated", updatedUser.Name)
 	assert.Equal(t, "Doe Updated", updatedUser.LastName)
 	mockUserClient.AssertExpectations(t)
 }
+
+func TestLogin_SuccessWithUsername(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+	mockRefreshTokenClient := mocks.refreshTokens
+	mockRefreshTokenClient.On("InsertRefreshToken", mock.Anything, mock.Anything).Return(model.RefreshToken{Id: 1}, nil)
+
+	hashedPassword, _ := userService.HashPassword("password123")
+	mockUser := model.User{Id: 1, UserName: "jdoe", Password: hashedPassword, Type: true}
+
+	mockUserClient.On("GetUserByUsername", mock.Anything, "jdoe").Return(mockUser, nil)
+
+	tokenDto, err := userService.Login(ctx, &dto.LoginDto{Login: "jdoe", Password: "password123"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer", tokenDto.TokenType)
+	assert.NotEmpty(t, tokenDto.RefreshToken)
+
+	claims, parseErr := testSigner.Parse(tokenDto.AccessToken)
+	assert.NoError(t, parseErr)
+	assert.Equal(t, 1, claims.UserId)
+	assert.Equal(t, token.RoleAdmin, claims.Role)
+	assert.Equal(t, claims.ExpiresAt.Unix(), tokenDto.ExpiresAt.Unix())
+	mockUserClient.AssertExpectations(t)
+}
+
+func TestLogin_SuccessWithEmail(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+	mockRefreshTokenClient := mocks.refreshTokens
+	mockRefreshTokenClient.On("InsertRefreshToken", mock.Anything, mock.Anything).Return(model.RefreshToken{Id: 1}, nil)
+
+	hashedPassword, _ := userService.HashPassword("password123")
+	mockUser := model.User{Id: 2, Email: "jdoe@example.com", Password: hashedPassword}
+
+	mockUserClient.On("FindUserByEmail", mock.Anything, "jdoe@example.com").Return(mockUser, nil)
+
+	tokenDto, err := userService.Login(ctx, &dto.LoginDto{Login: "jdoe@example.com", Password: "password123"})
+
+	assert.Nil(t, err)
+	claims, parseErr := testSigner.Parse(tokenDto.AccessToken)
+	assert.NoError(t, parseErr)
+	assert.Equal(t, 2, claims.UserId)
+	assert.Equal(t, token.RoleUser, claims.Role)
+	mockUserClient.AssertExpectations(t)
+}
+
+func TestLogin_WrongPassword(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	hashedPassword, _ := userService.HashPassword("password123")
+	mockUser := model.User{Id: 1, UserName: "jdoe", Password: hashedPassword}
+
+	mockUserClient.On("GetUserByUsername", mock.Anything, "jdoe").Return(mockUser, nil)
+
+	tokenDto, err := userService.Login(ctx, &dto.LoginDto{Login: "jdoe", Password: "wrong"})
+
+	assert.Nil(t, tokenDto)
+	assert.Equal(t, 401, err.Status())
+	assert.Equal(t, "Usuario o contraseña incorrectos", err.Message())
+	mockUserClient.AssertExpectations(t)
+}
+
+func TestLogin_UserNotFound(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserByUsername", mock.Anything, "ghost").Return(model.User{}, userClient.ErrNotFound)
+
+	tokenDto, err := userService.Login(ctx, &dto.LoginDto{Login: "ghost", Password: "password123"})
+
+	assert.Nil(t, tokenDto)
+	assert.Equal(t, 401, err.Status())
+	mockUserClient.AssertExpectations(t)
+
+	// The hash compared instead costs as much as the stored ones
+	cost, costErr := bcrypt.Cost([]byte(dummyPasswordHash))
+	assert.NoError(t, costErr)
+	assert.Equal(t, bcrypt.DefaultCost, cost)
+}
+
+func TestUpdateUser_NotFound(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserClient.On("GetUserById", mock.Anything, 2).Return(model.User{}, userClient.ErrNotFound)
+
+	updatedUser, err := userService.UpdateUser(ctx, 2, 1, &dto.UserUpdateDto{Name: "John"})
+
+	assert.Nil(t, updatedUser)
+	assert.Equal(t, "Usuario no encontrado", err.Message())
+	mockUserClient.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestInsertUser_ValidationErrors(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	mockUserDto := &dto.UserCreateDto{
+		Name:     "John",
+		UserName: "j doe",
+		Phone:    -1,
+		Email:    "not-an-email",
+		Password: "1",
+	}
+
+	user, err := userService.InsertUser(ctx, mockUserDto)
+
+	assert.Nil(t, user)
+	assert.Equal(t, 400, err.Status())
+	assert.Equal(t, "validation_error", err.Code())
+
+	fields := []string{}
+	for _, cause := range err.Cause() {
+		fields = append(fields, cause.(validation.FieldError).Field)
+	}
+	assert.ElementsMatch(t, []string{"last_name", "username", "phone", "password", "email"}, fields)
+	mockUserClient.AssertNotCalled(t, "EmailTaken", mock.Anything, mock.Anything)
+	mockUserClient.AssertNotCalled(t, "InsertUser", mock.Anything, mock.Anything)
+}
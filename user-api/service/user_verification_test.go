@@ -0,0 +1,162 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+	userClient "user-api/client"
+	"user-api/dto"
+	"user-api/model"
+	"user-api/notification"
+	"user-api/search"
+	"user-api/utils/token"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEmail_Success(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+	mockOneTimeTokenClient := mocks.oneTimeTokens
+
+	stored := model.OneTimeToken{Id: 5, UserId: 1, Purpose: model.PurposeEmailVerification, Email: "jdoe@example.com", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mockOneTimeTokenClient.On("GetOneTimeTokenByHash", mock.Anything, token.Hash("verify-token"), model.PurposeEmailVerification).Return(stored, nil)
+	mockOneTimeTokenClient.On("UseOneTimeToken", mock.Anything, 5).Return(true, nil)
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(model.User{Id: 1, Email: "jdoe@example.com"}, nil)
+	mockUserClient.On("UpdateUser", mock.Anything, mock.MatchedBy(func(user model.User) bool {
+		return user.EmailVerified
+	}), mock.Anything).Return(nil)
+
+	err := userService.VerifyEmail(ctx, "verify-token")
+
+	assert.Nil(t, err)
+	mockUserClient.AssertExpectations(t)
+	mockOneTimeTokenClient.AssertExpectations(t)
+}
+
+func TestVerifyEmail_InvalidToken(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockOneTimeTokenClient := mocks.oneTimeTokens
+
+	stored := model.OneTimeToken{Id: 5, UserId: 1, ExpiresAt: time.Now().Add(-time.Minute)}
+	mockOneTimeTokenClient.On("GetOneTimeTokenByHash", mock.Anything, token.Hash("verify-token"), model.PurposeEmailVerification).Return(stored, nil)
+
+	err := userService.VerifyEmail(ctx, "verify-token")
+
+	assert.Equal(t, "Token de verificación inválido o expirado", err.Message())
+}
+
+func TestVerifyEmail_OtherEmail(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+	mockOneTimeTokenClient := mocks.oneTimeTokens
+
+	stored := model.OneTimeToken{Id: 5, UserId: 1, Purpose: model.PurposeEmailVerification, Email: "old@example.com", ExpiresAt: time.Now().Add(time.Hour)}
+	mockOneTimeTokenClient.On("GetOneTimeTokenByHash", mock.Anything, token.Hash("verify-token"), model.PurposeEmailVerification).Return(stored, nil)
+	mockOneTimeTokenClient.On("UseOneTimeToken", mock.Anything, 5).Return(true, nil)
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(model.User{Id: 1, Email: "new@example.com"}, nil)
+
+	err := userService.VerifyEmail(ctx, "verify-token")
+
+	assert.Equal(t, "Token de verificación inválido o expirado", err.Message())
+	mockUserClient.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+// A token sent to the address the user signed up with must not verify the address
+// the user changed to afterwards.
+func TestVerifyEmail_AfterEmailChange(t *testing.T) {
+
+	store := userClient.NewMemoryStore()
+	users := userClient.NewMemoryUserClient(store)
+	refreshTokens := userClient.NewMemoryRefreshTokenClient(store)
+	notifier := &notification.MemoryNotifier{}
+	userService := NewUserService(
+		users,
+		refreshTokens,
+		userClient.NewMemoryOneTimeTokenClient(store),
+		NewTokenService(users, refreshTokens, testSigner, testRefreshTokenTTL),
+		notification.Dispatcher{Notifier: notifier},
+		search.NewMemoryIndex(nil),
+		DefaultUserSettings(),
+	)
+
+	// lastToken returns the token of the last verification email sent
+	lastToken := func() string {
+		messages := notifier.Messages()
+		body := strings.TrimSpace(messages[len(messages)-1].Body)
+		return body[strings.LastIndex(body, "\n")+1:]
+	}
+
+	created, apiErr := userService.InsertUser(ctx, &dto.UserCreateDto{Name: "John", LastName: "Doe", UserName: "jdoe", Email: "mine@example.com", Password: "password123"})
+	require.Nil(t, apiErr)
+	mineToken := lastToken()
+
+	_, apiErr = userService.UpdateUser(ctx, created.Id, 1, &dto.UserUpdateDto{Name: "John", LastName: "Doe", UserName: "jdoe", Email: "victim@example.com"})
+	require.Nil(t, apiErr)
+	victimToken := lastToken()
+	require.NotEqual(t, mineToken, victimToken)
+
+	apiErr = userService.VerifyEmail(ctx, mineToken)
+	require.NotNil(t, apiErr)
+	assert.Equal(t, "Token de verificación inválido o expirado", apiErr.Message())
+
+	user, _ := users.GetUserById(ctx, created.Id)
+	assert.Equal(t, "victim@example.com", user.Email)
+	assert.False(t, user.EmailVerified)
+
+	// The token sent to the current address still works
+	assert.Nil(t, userService.VerifyEmail(ctx, victimToken))
+	user, _ = users.GetUserById(ctx, created.Id)
+	assert.True(t, user.EmailVerified)
+}
+
+func TestLogin_UnverifiedEmailBlocked(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+
+	userService.settings.RequireVerifiedEmail = true
+
+	hashedPassword, _ := userService.HashPassword("password123")
+	mockUserClient.On("GetUserByUsername", mock.Anything, "jdoe").Return(model.User{Id: 1, Password: hashedPassword}, nil)
+
+	tokenDto, err := userService.Login(ctx, &dto.LoginDto{Login: "jdoe", Password: "password123"})
+
+	assert.Nil(t, tokenDto)
+	assert.Equal(t, 403, err.Status())
+	mockUserClient.AssertExpectations(t)
+}
+
+func TestUpdateUser_EmailChangeRequiresVerification(t *testing.T) {
+
+	userService, mocks := newTestUserService()
+	mockUserClient := mocks.users
+	mockOneTimeTokenClient := mocks.oneTimeTokens
+	notifier := mocks.notifier
+
+	mockUser := model.User{Id: 1, Name: "John", LastName: "Doe", UserName: "jdoe", Email: "old@example.com", EmailVerified: true, Version: 1}
+	mockUserClient.On("GetUserById", mock.Anything, 1).Return(mockUser, nil)
+	mockUserClient.On("EmailTaken", mock.Anything, "new@example.com").Return(false, nil)
+	mockUserClient.On("UpdateUser", mock.Anything, mock.MatchedBy(func(user model.User) bool {
+		return user.Email == "new@example.com" && !user.EmailVerified
+	}), mock.Anything).Return(nil)
+	mockOneTimeTokenClient.On("RevokeUserOneTimeTokens", mock.Anything, 1, model.PurposeEmailVerification).Return(nil)
+	mockOneTimeTokenClient.On("InsertOneTimeToken", mock.Anything, mock.MatchedBy(func(oneTimeToken model.OneTimeToken) bool {
+		return oneTimeToken.Email == "new@example.com"
+	})).Return(model.OneTimeToken{Id: 1}, nil)
+
+	updatedUser, err := userService.UpdateUser(ctx, 1, 1, &dto.UserUpdateDto{Name: "John", LastName: "Doe", UserName: "jdoe", Email: "new@example.com"})
+
+	assert.Nil(t, err)
+	assert.False(t, updatedUser.EmailVerified)
+	assert.Len(t, notifier.Messages(), 1)
+	assert.Equal(t, "new@example.com", notifier.Messages()[0].To)
+	mockUserClient.AssertExpectations(t)
+	mockOneTimeTokenClient.AssertExpectations(t)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	json "github.com/json-iterator/go"
 	"net/http"
+	"user-api/utils/i18n"
 )
 
 type CauseList []interface{}
@@ -21,6 +22,10 @@ type apiErr struct {
 	ErrorCode    string    `json:"error"`
 	ErrorStatus  int       `json:"status"`
 	ErrorCause   CauseList `json:"cause"`
+
+	// The message the error was built from, to translate it
+	message Message
+	params  []interface{}
 }
 
 func (c CauseList) ToString() string {
@@ -47,48 +52,73 @@ func (e apiErr) Message() string {
 	return e.ErrorMessage
 }
 
-func NewApiError(message string, error string, status int, cause CauseList) ApiError {
-	return apiErr{message, error, status, cause}
+// newApiErr builds an error with its message in the default language.
+func newApiErr(message Message, params []interface{}, error string, status int, cause CauseList) apiErr {
+	return apiErr{Translate(i18n.Default, message, params...), error, status, cause, message, params}
+}
+
+func NewApiError(message Message, error string, status int, cause CauseList) ApiError {
+	return newApiErr(message, nil, error, status, cause)
 }
 
-func NewNotFoundApiError(message string) ApiError {
-	return apiErr{message, "not_found", http.StatusNotFound, CauseList{}}
+func NewNotFoundApiError(message Message, params ...interface{}) ApiError {
+	return newApiErr(message, params, "not_found", http.StatusNotFound, CauseList{})
 }
 
-func NewTooManyRequestsError(message string) ApiError {
-	return apiErr{message, "too_many_requests", http.StatusTooManyRequests, CauseList{}}
+func NewTooManyRequestsError(message Message, params ...interface{}) ApiError {
+	return newApiErr(message, params, "too_many_requests", http.StatusTooManyRequests, CauseList{})
 }
 
-func NewBadRequestApiError(message string) ApiError {
-	return apiErr{message, "bad_request", http.StatusBadRequest, CauseList{}}
+func NewBadRequestApiError(message Message, params ...interface{}) ApiError {
+	return newApiErr(message, params, "bad_request", http.StatusBadRequest, CauseList{})
 }
 
-func NewValidationApiError(message string, error string, cause CauseList) ApiError {
-	return apiErr{message, error, http.StatusBadRequest, cause}
+func NewValidationApiError(message Message, error string, cause CauseList) ApiError {
+	return newApiErr(message, nil, error, http.StatusBadRequest, cause)
 }
 
 func NewMethodNotAllowedApiError() ApiError {
-	return apiErr{"Method not allowed", "method_not_allowed", http.StatusMethodNotAllowed, CauseList{}}
+	return newApiErr(MsgMethodNotAllowed, nil, "method_not_allowed", http.StatusMethodNotAllowed, CauseList{})
+}
+
+func NewInternalServerApiError(message Message, err error) ApiError {
+	cause := CauseList{}
+	if err != nil {
+		cause = append(cause, err.Error())
+	}
+	return newApiErr(message, nil, "internal_server_error", http.StatusInternalServerError, cause)
+}
+
+func NewForbiddenApiError(message Message, params ...interface{}) ApiError {
+	return newApiErr(message, params, "forbidden", http.StatusForbidden, CauseList{})
+}
+
+func NewUnauthorizedApiError(message Message, params ...interface{}) ApiError {
+	return newApiErr(message, params, "unauthorized_scopes", http.StatusUnauthorized, CauseList{})
+}
+
+func NewDuplicateApiError(message Message, cause CauseList) ApiError {
+	return newApiErr(message, nil, "duplicate_key", http.StatusConflict, cause)
 }
 
-func NewInternalServerApiError(message string, err error) ApiError {
+func NewServiceUnavailableApiError(message Message, err error) ApiError {
 	cause := CauseList{}
 	if err != nil {
 		cause = append(cause, err.Error())
 	}
-	return apiErr{message, "internal_server_error", http.StatusInternalServerError, cause}
+	return newApiErr(message, nil, "service_unavailable", http.StatusServiceUnavailable, cause)
 }
 
-func NewForbiddenApiError(message string) ApiError {
-	return apiErr{message, "forbidden", http.StatusForbidden, CauseList{}}
+func NewPreconditionRequiredApiError(message Message, params ...interface{}) ApiError {
+	return newApiErr(message, params, "precondition_required", http.StatusPreconditionRequired, CauseList{})
 }
 
-func NewUnauthorizedApiError(message string) ApiError {
-	return apiErr{message, "unauthorized_scopes", http.StatusUnauthorized, CauseList{}}
+func NewPreconditionFailedApiError(message Message, params ...interface{}) ApiError {
+	return newApiErr(message, params, "precondition_failed", http.StatusPreconditionFailed, CauseList{})
 }
 
 func NewConflictApiError(id string) ApiError {
-	return apiErr{"Can't update " + id + " due to a conflict error", "conflict_error", http.StatusConflict, CauseList{}}
+	return newApiErr(MsgConflict, []interface{}{id}, "conflict_error", http.StatusConflict, CauseList{})
 }
 
 func NewApiErrorFromBytes(data []byte) (ApiError, error) {
@@ -0,0 +1,243 @@
+package errors
+
+import (
+	"fmt"
+	"user-api/utils/i18n"
+)
+
+// LocalizedCause is implemented by the causes that carry a message for the client,
+// so that it is translated along with the error.
+type LocalizedCause interface {
+	Localize(language i18n.Language) interface{}
+}
+
+// Message identifies a message for the client by a stable id, so that its
+// translations don't depend on how it is worded in the code. Messages are only
+// created by newMessage, which keeps the list the catalogs are tested against.
+type Message struct {
+	id string
+}
+
+var registered []Message
+
+func newMessage(id string) Message {
+	message := Message{id}
+	registered = append(registered, message)
+	return message
+}
+
+// Id returns the stable id of the message.
+func (m Message) Id() string {
+	return m.id
+}
+
+var (
+	MsgInvalidData              = newMessage("invalid_data")
+	MsgInvalidParams            = newMessage("invalid_params")
+	MsgInvalidCursor            = newMessage("invalid_cursor")
+	MsgInvalidUserId            = newMessage("invalid_user_id")
+	MsgInvalidIfMatch           = newMessage("invalid_if_match")
+	MsgInvalidPatch             = newMessage("invalid_patch")
+	MsgWrongCurrentPassword     = newMessage("wrong_current_password")
+	MsgPasswordNotAllowed       = newMessage("password_not_allowed")
+	MsgInvalidResetToken        = newMessage("invalid_reset_token")
+	MsgInvalidVerificationToken = newMessage("invalid_verification_token")
+	MsgVerificationTokenMissing = newMessage("verification_token_missing")
+
+	MsgAccessTokenMissing   = newMessage("access_token_missing")
+	MsgInvalidAccessToken   = newMessage("invalid_access_token")
+	MsgInvalidRefreshToken  = newMessage("invalid_refresh_token")
+	MsgExpiredRefreshToken  = newMessage("expired_refresh_token")
+	MsgWrongCredentials     = newMessage("wrong_credentials")
+	MsgEmailNotVerified     = newMessage("email_not_verified")
+	MsgForbidden            = newMessage("forbidden")
+	MsgResourceNotFound     = newMessage("resource_not_found")
+	MsgUserNotFound         = newMessage("user_not_found")
+	MsgDeletedUserNotFound  = newMessage("deleted_user_not_found")
+	MsgMethodNotAllowed     = newMessage("method_not_allowed")
+	MsgEmailTaken           = newMessage("email_taken")
+	MsgUserNameTaken        = newMessage("user_name_taken")
+	MsgValueTaken           = newMessage("value_taken")
+	MsgConflict             = newMessage("conflict")
+	MsgIfMatchRequired      = newMessage("if_match_required")
+	MsgWeakETag             = newMessage("weak_etag")
+	MsgUnsupportedPatchType = newMessage("unsupported_patch_type")
+	MsgServiceUnavailable   = newMessage("service_unavailable")
+
+	MsgInternalError           = newMessage("internal_error")
+	MsgCouldNotValidateData    = newMessage("could_not_validate_data")
+	MsgCouldNotGetUser         = newMessage("could_not_get_user")
+	MsgCouldNotGetUsers        = newMessage("could_not_get_users")
+	MsgCouldNotSearch          = newMessage("could_not_search")
+	MsgCouldNotRegisterUser    = newMessage("could_not_register_user")
+	MsgCouldNotUpdateUser      = newMessage("could_not_update_user")
+	MsgCouldNotDeleteUser      = newMessage("could_not_delete_user")
+	MsgCouldNotRestoreUser     = newMessage("could_not_restore_user")
+	MsgCouldNotPurgeUsers      = newMessage("could_not_purge_users")
+	MsgCouldNotLogin           = newMessage("could_not_login")
+	MsgCouldNotLogout          = newMessage("could_not_logout")
+	MsgCouldNotGenerateToken   = newMessage("could_not_generate_token")
+	MsgCouldNotRefreshToken    = newMessage("could_not_refresh_token")
+	MsgCouldNotValidateToken   = newMessage("could_not_validate_token")
+	MsgCouldNotRevokeSession   = newMessage("could_not_revoke_session")
+	MsgCouldNotCloseSessions   = newMessage("could_not_close_sessions")
+	MsgCouldNotUpdatePassword  = newMessage("could_not_update_password")
+	MsgCouldNotResetPassword   = newMessage("could_not_reset_password")
+	MsgCouldNotVerifyEmail     = newMessage("could_not_verify_email")
+	MsgCouldNotGetAuditEntries = newMessage("could_not_get_audit_entries")
+)
+
+// messages are the catalogs of the messages, keyed by language and message id.
+// Messages with parameters are fmt formats and take them in the same order in
+// every language.
+var messages = map[i18n.Language]map[string]string{
+	i18n.Spanish: {
+		"invalid_data":               "Datos inválidos",
+		"invalid_params":             "Parámetros inválidos",
+		"invalid_cursor":             "Cursor inválido",
+		"invalid_user_id":            "Id de usuario inválido",
+		"invalid_if_match":           "Encabezado If-Match inválido",
+		"invalid_patch":              "Patch inválido: %s",
+		"wrong_current_password":     "La contraseña actual es incorrecta",
+		"password_not_allowed":       "No se puede utilizar esa contraseña",
+		"invalid_reset_token":        "Token de restablecimiento inválido o expirado",
+		"invalid_verification_token": "Token de verificación inválido o expirado",
+		"verification_token_missing": "Token de verificación requerido",
+
+		"access_token_missing":   "Token de acceso requerido",
+		"invalid_access_token":   "Token de acceso inválido o expirado",
+		"invalid_refresh_token":  "Refresh token inválido",
+		"expired_refresh_token":  "Refresh token expirado",
+		"wrong_credentials":      "Usuario o contraseña incorrectos",
+		"email_not_verified":     "Debe verificar su email antes de iniciar sesión",
+		"forbidden":              "No tiene permisos para realizar esta acción",
+		"resource_not_found":     "Recurso no encontrado",
+		"user_not_found":         "Usuario no encontrado",
+		"deleted_user_not_found": "Usuario eliminado no encontrado",
+		"method_not_allowed":     "Método no permitido",
+		"email_taken":            "El email ya está registrado",
+		"user_name_taken":        "Nombre de usuario repetido",
+		"value_taken":            "El valor ya está registrado",
+		"conflict":               "No se puede modificar %s: fue modificado por otra solicitud, vuelva a cargarlo e intente nuevamente",
+		"if_match_required":      "Se requiere el encabezado If-Match con el ETag del usuario",
+		"weak_etag":              "If-Match solo admite el ETag fuerte del usuario",
+		"unsupported_patch_type": "Tipo de patch no soportado",
+		"service_unavailable":    "Servicio no disponible, intente nuevamente más tarde",
+
+		"internal_error":              "Error interno del servidor",
+		"could_not_validate_data":     "No se pudieron validar los datos",
+		"could_not_get_user":          "No se pudo obtener el usuario",
+		"could_not_get_users":         "No se pudieron obtener los usuarios",
+		"could_not_search":            "No se pudo realizar la búsqueda",
+		"could_not_register_user":     "No se pudo registrar el usuario",
+		"could_not_update_user":       "No se pudo actualizar el usuario",
+		"could_not_delete_user":       "No se pudo eliminar el usuario",
+		"could_not_restore_user":      "No se pudo restaurar el usuario",
+		"could_not_purge_users":       "No se pudieron purgar los usuarios eliminados",
+		"could_not_login":             "No se pudo iniciar sesión",
+		"could_not_logout":            "No se pudo cerrar la sesión",
+		"could_not_generate_token":    "No se pudo generar el token",
+		"could_not_refresh_token":     "No se pudo renovar el token",
+		"could_not_validate_token":    "No se pudo validar el token",
+		"could_not_revoke_session":    "No se pudo revocar la sesión",
+		"could_not_close_sessions":    "No se pudieron cerrar las sesiones",
+		"could_not_update_password":   "No se pudo actualizar la contraseña",
+		"could_not_reset_password":    "No se pudo restablecer la contraseña",
+		"could_not_verify_email":      "No se pudo verificar el email",
+		"could_not_get_audit_entries": "No se pudo obtener el registro de auditoría",
+	},
+	i18n.English: {
+		"invalid_data":               "Invalid data",
+		"invalid_params":             "Invalid parameters",
+		"invalid_cursor":             "Invalid cursor",
+		"invalid_user_id":            "Invalid user id",
+		"invalid_if_match":           "Invalid If-Match header",
+		"invalid_patch":              "Invalid patch: %s",
+		"wrong_current_password":     "The current password is incorrect",
+		"password_not_allowed":       "That password cannot be used",
+		"invalid_reset_token":        "Invalid or expired reset token",
+		"invalid_verification_token": "Invalid or expired verification token",
+		"verification_token_missing": "Verification token required",
+
+		"access_token_missing":   "Access token required",
+		"invalid_access_token":   "Invalid or expired access token",
+		"invalid_refresh_token":  "Invalid refresh token",
+		"expired_refresh_token":  "Expired refresh token",
+		"wrong_credentials":      "Incorrect username or password",
+		"email_not_verified":     "You must verify your email before logging in",
+		"forbidden":              "You are not allowed to perform this action",
+		"resource_not_found":     "Resource not found",
+		"user_not_found":         "User not found",
+		"deleted_user_not_found": "Deleted user not found",
+		"method_not_allowed":     "Method not allowed",
+		"email_taken":            "The email is already registered",
+		"user_name_taken":        "The username is already taken",
+		"value_taken":            "The value is already registered",
+		"conflict":               "Can't update %s due to a conflict error",
+		"if_match_required":      "The If-Match header with the ETag of the user is required",
+		"weak_etag":              "If-Match only accepts the strong ETag of the user",
+		"unsupported_patch_type": "Unsupported patch type",
+		"service_unavailable":    "Service unavailable, please try again later",
+
+		"internal_error":              "Internal server error",
+		"could_not_validate_data":     "Could not validate the data",
+		"could_not_get_user":          "Could not get the user",
+		"could_not_get_users":         "Could not get the users",
+		"could_not_search":            "Could not run the search",
+		"could_not_register_user":     "Could not register the user",
+		"could_not_update_user":       "Could not update the user",
+		"could_not_delete_user":       "Could not delete the user",
+		"could_not_restore_user":      "Could not restore the user",
+		"could_not_purge_users":       "Could not purge the deleted users",
+		"could_not_login":             "Could not log in",
+		"could_not_logout":            "Could not log out",
+		"could_not_generate_token":    "Could not generate the token",
+		"could_not_refresh_token":     "Could not refresh the token",
+		"could_not_validate_token":    "Could not validate the token",
+		"could_not_revoke_session":    "Could not revoke the session",
+		"could_not_close_sessions":    "Could not close the sessions",
+		"could_not_update_password":   "Could not update the password",
+		"could_not_reset_password":    "Could not reset the password",
+		"could_not_verify_email":      "Could not verify the email",
+		"could_not_get_audit_entries": "Could not get the audit log",
+	},
+}
+
+// Translate returns a message in a language with its parameters. A message the
+// language has no entry for is sent in the default language, and one missing
+// from both as its id.
+func Translate(language i18n.Language, message Message, params ...interface{}) string {
+	format, ok := messages[language][message.id]
+	if !ok {
+		format, ok = messages[i18n.Default][message.id]
+	}
+	if !ok {
+		return message.id
+	}
+	if len(params) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, params...)
+}
+
+// Localize returns the error with its message, and those of its causes, in the
+// given language. Errors that weren't built from a Message, such as those read
+// with NewApiErrorFromBytes, keep their message.
+func Localize(err ApiError, language i18n.Language) ApiError {
+	cause := append(CauseList{}, err.Cause()...)
+	for i, c := range cause {
+		if localized, ok := c.(LocalizedCause); ok {
+			cause[i] = localized.Localize(language)
+		}
+	}
+
+	translatable, ok := err.(apiErr)
+	if !ok {
+		translatable = apiErr{err.Message(), err.Code(), err.Status(), nil, Message{}, nil}
+	}
+	translatable.ErrorCause = cause
+	if translatable.message.id != "" {
+		translatable.ErrorMessage = Translate(language, translatable.message, translatable.params...)
+	}
+	return translatable
+}
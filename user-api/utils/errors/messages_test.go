@@ -0,0 +1,53 @@
+package errors
+
+import (
+	"regexp"
+	"testing"
+	"user-api/utils/i18n"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var verbs = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z]`)
+
+// Every message the code can build has to be in every catalog, taking the same
+// parameters.
+func TestMessages_Translated(t *testing.T) {
+	ids := map[string]bool{}
+	for _, message := range registered {
+		assert.False(t, ids[message.id], "message %q is declared twice", message.id)
+		ids[message.id] = true
+
+		spanish, ok := messages[i18n.Spanish][message.id]
+		if !assert.True(t, ok, "message %q has no Spanish text", message.id) {
+			continue
+		}
+		english, ok := messages[i18n.English][message.id]
+		if !assert.True(t, ok, "message %q has no English translation", message.id) {
+			continue
+		}
+		assert.Equal(t, verbs.FindAllString(spanish, -1), verbs.FindAllString(english, -1), message.id)
+	}
+
+	for language, catalog := range messages {
+		for id := range catalog {
+			assert.True(t, ids[id], "%s catalog has %q, which no message uses", language, id)
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	err := NewBadRequestApiError(MsgInvalidPatch, "unexpected EOF")
+	assert.Equal(t, "Patch inválido: unexpected EOF", err.Message())
+
+	localized := Localize(err, i18n.English)
+	assert.Equal(t, "Invalid patch: unexpected EOF", localized.Message())
+	assert.Equal(t, "bad_request", localized.Code())
+
+	// Localizing again starts from the message, not from the translated text
+	assert.Equal(t, "Patch inválido: unexpected EOF", Localize(localized, i18n.Spanish).Message())
+
+	// Errors read back from JSON have no message to translate
+	read, _ := NewApiErrorFromBytes([]byte(`{"message":"Usuario no encontrado","error":"not_found","status":404}`))
+	assert.Equal(t, "Usuario no encontrado", Localize(read, i18n.English).Message())
+}
@@ -0,0 +1,115 @@
+package errors
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ProblemMediaType is the media type of RFC 9457 problem details.
+const ProblemMediaType = "application/problem+json"
+
+// ProblemTypeBase prefixes the type URI of every problem. The rest of the URI is
+// derived from the error code and must not change once published.
+const ProblemTypeBase = "urn:user-api:problem:"
+
+// problemTypes maps the error codes to the last segment of their type URI. Codes
+// not listed here get "about:blank", the RFC 9457 type for a plain HTTP status.
+var problemTypes = map[string]string{
+	"bad_request":            "bad-request",
+	"validation_error":       "validation-error",
+	"unauthorized_scopes":    "unauthorized",
+	"forbidden":              "forbidden",
+	"not_found":              "not-found",
+	"method_not_allowed":     "method-not-allowed",
+	"conflict_error":         "conflict",
+	"duplicate_key":          "duplicate-value",
+	"precondition_required":  "precondition-required",
+	"unsupported_media_type": "unsupported-media-type",
+	"too_many_requests":      "too-many-requests",
+	"internal_server_error":  "internal-error",
+	"service_unavailable":    "service-unavailable",
+}
+
+// ParamCause is implemented by the causes that point at a parameter of the request.
+// Problem details list them as invalid-params; other causes are left out.
+type ParamCause interface {
+	ParamName() string
+	ParamReason() string
+}
+
+// InvalidParam is an entry of the invalid-params extension.
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// ProblemDetails is an ApiError as RFC 9457 problem details, with the error code,
+// the request id and the invalid parameters as extensions.
+type ProblemDetails struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	Code          string         `json:"code"`
+	RequestId     string         `json:"request_id,omitempty"`
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+}
+
+// ProblemType returns the type URI for an error code.
+func ProblemType(code string) string {
+	if slug, ok := problemTypes[code]; ok {
+		return ProblemTypeBase + slug
+	}
+	return "about:blank"
+}
+
+// NewProblemDetails converts the ApiError. instance is the path of the request.
+func NewProblemDetails(err ApiError, instance string, requestId string) ProblemDetails {
+	problem := ProblemDetails{
+		Type:      ProblemType(err.Code()),
+		Title:     http.StatusText(err.Status()),
+		Status:    err.Status(),
+		Detail:    err.Message(),
+		Instance:  instance,
+		Code:      err.Code(),
+		RequestId: requestId,
+	}
+
+	for _, cause := range err.Cause() {
+		if param, ok := cause.(ParamCause); ok {
+			problem.InvalidParams = append(problem.InvalidParams, InvalidParam{Name: param.ParamName(), Reason: param.ParamReason()})
+		}
+	}
+	return problem
+}
+
+// PrefersProblemDetails tells whether an Accept header asks for problem details
+// over plain JSON. Wildcards keep the plain JSON shape, so only callers that name
+// application/problem+json get it.
+func PrefersProblemDetails(accept string) bool {
+	problemQ, jsonQ := -1.0, -1.0
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case ProblemMediaType:
+			problemQ = q
+		case "application/json":
+			jsonQ = q
+		}
+	}
+	return problemQ > 0 && problemQ >= jsonQ
+}
@@ -0,0 +1,25 @@
+package errors
+
+// ResponseBody is the JSON body of an error response: the fields of the ApiError
+// and the id of the request that failed.
+type ResponseBody struct {
+	Message   string    `json:"message"`
+	Error     string    `json:"error"`
+	Status    int       `json:"status"`
+	Cause     CauseList `json:"cause"`
+	RequestId string    `json:"request_id,omitempty"`
+}
+
+func NewResponseBody(err ApiError, requestId string) ResponseBody {
+	cause := err.Cause()
+	if cause == nil {
+		cause = CauseList{}
+	}
+	return ResponseBody{
+		Message:   err.Message(),
+		Error:     err.Code(),
+		Status:    err.Status(),
+		Cause:     cause,
+		RequestId: requestId,
+	}
+}
@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Language is the primary subtag of a language the API has messages in.
+type Language string
+
+const (
+	Spanish Language = "es"
+	English Language = "en"
+
+	// Default is the language of the messages in the code, used when the client
+	// doesn't ask for a supported one.
+	Default = Spanish
+)
+
+var supported = map[Language]bool{Spanish: true, English: true}
+
+// FromAcceptLanguage picks the supported language the client prefers in an
+// Accept-Language header, comparing only primary subtags so that "en-US" gets
+// English. Ties keep the order of the header.
+func FromAcceptLanguage(header string) Language {
+	type candidate struct {
+		language Language
+		q        float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(name, "q") {
+				parsed, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		language := Language(strings.SplitN(tag, "-", 2)[0])
+		if tag == "*" {
+			language = Default
+		}
+		if supported[language] {
+			candidates = append(candidates, candidate{language, q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Default
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].language
+}
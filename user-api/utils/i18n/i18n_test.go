@@ -0,0 +1,30 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header   string
+		language Language
+	}{
+		{"", Spanish},
+		{"*", Spanish},
+		{"en", English},
+		{"en-US,en;q=0.9", English},
+		{"EN-gb", English},
+		{"es-AR,es;q=0.9,en;q=0.8", Spanish},
+		{"en;q=0.5, es;q=0.8", Spanish},
+		{"fr-FR, en;q=0.7", English},
+		{"fr, de", Spanish},
+		{"en;q=0, es;q=0.1", Spanish},
+		{"en, es", English},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.language, FromAcceptLanguage(test.header), test.header)
+	}
+}
@@ -0,0 +1,49 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header is the HTTP header that carries the request id, in both directions.
+const Header = "X-Request-ID"
+
+// MaxLength bounds the ids accepted from callers, which end up in logs and bodies.
+const MaxLength = 128
+
+type contextKey struct{}
+
+// New returns a random request id.
+func New() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// Valid tells whether an id sent by a caller can be used as is: short and made of
+// printable ASCII only.
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext returns a copy of ctx carrying the request id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request id carried by ctx, or "" if there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
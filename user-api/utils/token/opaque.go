@@ -0,0 +1,23 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// NewOpaque returns a random URL-safe token. Only its hash should be persisted.
+func NewOpaque() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// Hash returns the hex encoded SHA-256 of an opaque token, as stored in the database.
+func Hash(opaque string) string {
+	sum := sha256.Sum256([]byte(opaque))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,77 @@
+package token
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Claims are the custom claims carried by every access token.
+type Claims struct {
+	UserId int    `json:"user_id"`
+	Role   string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// RoleFromType maps the model.User Type flag to the role claim.
+func RoleFromType(admin bool) string {
+	if admin {
+		return RoleAdmin
+	}
+	return RoleUser
+}
+
+// Signer signs and validates the access tokens with a secret.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSigner returns a signer whose tokens stay valid for accessTokenTTL after issuance.
+func NewSigner(secret []byte, accessTokenTTL time.Duration) *Signer {
+	return &Signer{secret: secret, ttl: accessTokenTTL}
+}
+
+// Generate signs a new access token for the given user and returns it with its expiry.
+func (s *Signer) Generate(userId int, admin bool) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.ttl)
+
+	claims := Claims{
+		UserId: userId,
+		Role:   RoleFromType(admin),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userId),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// Parse validates the signature and expiry of an access token and returns its claims.
+func (s *Signer) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if claims.UserId == 0 {
+		return nil, errors.New("token without user id")
+	}
+	return claims, nil
+}
@@ -0,0 +1,167 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+	e "user-api/utils/errors"
+	"user-api/utils/i18n"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	PasswordMinLength = 8
+	PasswordMaxLength = 72 // bcrypt ignores anything after 72 bytes
+)
+
+// FieldError is the cause added to a validation ApiError for every failing field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+
+	// text tells whether the field is a string, which changes the message of min and max
+	text bool
+}
+
+// ParamName and ParamReason list the field in the invalid-params of problem details.
+func (f FieldError) ParamName() string   { return f.Field }
+func (f FieldError) ParamReason() string { return f.Message }
+
+// Localize returns the field error with its message in the given language. The
+// default language is left alone, as field errors built by hand, like those of
+// unique columns, carry their own message in it.
+func (f FieldError) Localize(language i18n.Language) interface{} {
+	if language != i18n.Default {
+		f.Message = message(language, f.Rule, f.Param, f.text)
+	}
+	return f
+}
+
+var (
+	validate = validator.New(validator.WithRequiredStructEnabled())
+
+	usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+)
+
+func init() {
+	// Report fields by their JSON name, as the client sent them
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	validate.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return usernamePattern.MatchString(fl.Field().String())
+	})
+
+	validate.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return IsStrongPassword(fl.Field().String())
+	})
+}
+
+// IsStrongPassword checks the password policy: between 8 and 72 bytes with at
+// least one letter and one digit.
+func IsStrongPassword(password string) bool {
+	if len(password) < PasswordMinLength || len(password) > PasswordMaxLength {
+		return false
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		hasLetter = hasLetter || unicode.IsLetter(r)
+		hasDigit = hasDigit || unicode.IsDigit(r)
+	}
+	return hasLetter && hasDigit
+}
+
+// Struct validates the `validate` tags of a DTO and returns a validation ApiError
+// with one FieldError per failing field, or nil if the DTO is valid.
+func Struct(dto interface{}) e.ApiError {
+	err := validate.Struct(dto)
+	if err == nil {
+		return nil
+	}
+
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return e.NewInternalServerApiError(e.MsgCouldNotValidateData, err)
+	}
+
+	causes := e.CauseList{}
+	for _, fieldError := range validationErrors {
+		text := fieldError.Kind() == reflect.String
+		causes = append(causes, FieldError{
+			Field:   fieldError.Field(),
+			Rule:    fieldError.Tag(),
+			Param:   fieldError.Param(),
+			Message: message(i18n.Default, fieldError.Tag(), fieldError.Param(), text),
+			text:    text,
+		})
+	}
+	return e.NewValidationApiError(e.MsgInvalidData, "validation_error", causes)
+}
+
+// ruleMessages are the catalogs of the field error messages, keyed by language and
+// rule. min and max have their own entries for strings, and "" is the message of
+// the rules without one.
+var ruleMessages = map[i18n.Language]map[string]string{
+	i18n.Spanish: {
+		"":         "Valor inválido",
+		"required": "Es obligatorio",
+		"max":      "Debe ser como máximo %s",
+		"max_text": "Debe tener como máximo %s caracteres",
+		"min":      "Debe ser como mínimo %s",
+		"min_text": "Debe tener al menos %s caracteres",
+		"email":    "Debe ser un email válido",
+		"oneof":    "Debe ser uno de: %s",
+		"username": "Solo puede contener letras, números, '.', '_' y '-'",
+		"password": "Debe tener entre %d y %d caracteres, al menos una letra y un número",
+	},
+	i18n.English: {
+		"":         "Invalid value",
+		"required": "Is required",
+		"max":      "Must be at most %s",
+		"max_text": "Must be at most %s characters long",
+		"min":      "Must be at least %s",
+		"min_text": "Must be at least %s characters long",
+		"email":    "Must be a valid email",
+		"oneof":    "Must be one of: %s",
+		"username": "Can only contain letters, numbers, '.', '_' and '-'",
+		"password": "Must be between %d and %d characters long, with at least one letter and one number",
+		"unique":   "Is already registered",
+	},
+}
+
+func ruleKey(rule string, text bool) string {
+	if text && (rule == "min" || rule == "max") {
+		return rule + "_text"
+	}
+	return rule
+}
+
+func message(language i18n.Language, rule string, param string, text bool) string {
+	catalog := ruleMessages[language]
+	format, ok := catalog[ruleKey(rule, text)]
+	if !ok {
+		return catalog[""]
+	}
+
+	switch rule {
+	case "min", "max":
+		return fmt.Sprintf(format, param)
+	case "oneof":
+		return fmt.Sprintf(format, strings.ReplaceAll(param, " ", ", "))
+	case "password":
+		return fmt.Sprintf(format, PasswordMinLength, PasswordMaxLength)
+	default:
+		return format
+	}
+}
@@ -0,0 +1,56 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+	"user-api/utils/i18n"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDto struct {
+	Name     string `json:"name" validate:"required,max=5"`
+	UserName string `json:"username" validate:"required,username"`
+	Password string `json:"password" validate:"required,password"`
+	Phone    int    `json:"phone" validate:"min=0"`
+}
+
+func TestStruct_Valid(t *testing.T) {
+	assert.Nil(t, Struct(&testDto{Name: "José", UserName: "jose.perez_1", Password: "secreto123"}))
+}
+
+func TestStruct_FieldErrors(t *testing.T) {
+	err := Struct(&testDto{Name: "Josefina", UserName: "jose perez", Password: "secreto", Phone: -5})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 400, err.Status())
+	assert.Equal(t, "validation_error", err.Code())
+	assert.Equal(t, []interface{}{
+		FieldError{Field: "name", Rule: "max", Param: "5", Message: "Debe tener como máximo 5 caracteres", text: true},
+		FieldError{Field: "username", Rule: "username", Message: "Solo puede contener letras, números, '.', '_' y '-'", text: true},
+		FieldError{Field: "password", Rule: "password", Message: "Debe tener entre 8 y 72 caracteres, al menos una letra y un número", text: true},
+		FieldError{Field: "phone", Rule: "min", Param: "0", Message: "Debe ser como mínimo 0"},
+	}, []interface{}(err.Cause()))
+}
+
+func TestIsStrongPassword(t *testing.T) {
+	assert.True(t, IsStrongPassword("password123"))
+	assert.False(t, IsStrongPassword("password"))
+	assert.False(t, IsStrongPassword("12345678"))
+	assert.False(t, IsStrongPassword("pass1"))
+	assert.False(t, IsStrongPassword(strings.Repeat("a1", 40)))
+}
+
+func TestFieldError_Localize(t *testing.T) {
+	err := Struct(&testDto{Name: "Josefina", UserName: "jose", Password: "secreto123", Phone: -5})
+
+	english := []interface{}{}
+	for _, cause := range err.Cause() {
+		english = append(english, cause.(FieldError).Localize(i18n.English).(FieldError).Message)
+	}
+	assert.Equal(t, []interface{}{"Must be at most 5 characters long", "Must be at least 0"}, english)
+
+	unique := FieldError{Field: "email", Rule: "unique", Message: "El email ya está registrado"}
+	assert.Equal(t, unique, unique.Localize(i18n.Spanish))
+	assert.Equal(t, "Is already registered", unique.Localize(i18n.English).(FieldError).Message)
+}